@@ -0,0 +1,162 @@
+package gleanreceiver
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Reasons a ping is refused by admission control
+const (
+	refusedInFlightPings = "in_flight_pings"
+	refusedInFlightBytes = "in_flight_bytes"
+	refusedMemoryLimiter = "memory_limiter"
+)
+
+// errInFlightBytesExceeded is returned while reading a compressed body or one
+// without a Content-Length once the buffered bytes exceed the budget
+var errInFlightBytesExceeded = errors.New("in-flight bytes exceeded")
+
+// memoryLimiter is implemented by the memory_limiter extension
+type memoryLimiter interface {
+	MustRefuse() bool
+}
+
+// admissionController bounds the pings the HTTP server processes and the
+// request bytes it buffers at once
+type admissionController struct {
+	maxPings      int
+	maxBytes      int64
+	memoryLimiter memoryLimiter
+
+	mu    sync.Mutex
+	pings int
+	bytes int64
+}
+
+// newAdmissionController returns nil when admission control is not configured
+func newAdmissionController(cfg AdmissionConfig) *admissionController {
+	if cfg.MaxInFlightPings <= 0 && cfg.MaxInFlightBytes <= 0 && cfg.MemoryLimiter == nil {
+		return nil
+	}
+	return &admissionController{
+		maxPings: cfg.MaxInFlightPings,
+		maxBytes: cfg.MaxInFlightBytes,
+	}
+}
+
+// resolveMemoryLimiter looks up the memory limiter extension among the
+// extensions of the host
+func (a *admissionController) resolveMemoryLimiter(host component.Host, id component.ID) error {
+	extension, ok := host.GetExtensions()[id]
+	if !ok {
+		return fmt.Errorf("memory limiter extension %s not found", id)
+	}
+	limiter, ok := extension.(memoryLimiter)
+	if !ok {
+		return fmt.Errorf("extension %s is not a memory limiter", id)
+	}
+	a.memoryLimiter = limiter
+	return nil
+}
+
+// admit reserves a slot and the declared body size for a request. It returns
+// the admitted request, to be released once the ping is processed, or the
+// reason the request is refused. The budget bounds the bytes buffered, which
+// are the decompressed bytes, so compressed bodies and bodies without a
+// Content-Length are reserved as they are read, see admittedRequest.reader.
+func (a *admissionController) admit(req *http.Request) (*admittedRequest, string) {
+	if a.memoryLimiter != nil && a.memoryLimiter.MustRefuse() {
+		return nil, refusedMemoryLimiter
+	}
+
+	counted := req.ContentLength < 0 || !isIdentityEncoding(req.Header.Get("Content-Encoding"))
+	var size int64
+	if !counted {
+		size = req.ContentLength
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.maxPings > 0 && a.pings >= a.maxPings {
+		return nil, refusedInFlightPings
+	}
+	// A ping arriving while nothing else is buffered is always admitted, so
+	// pings larger than the budget are not refused forever
+	if a.maxBytes > 0 && a.pings > 0 && a.bytes+size > a.maxBytes {
+		return nil, refusedInFlightBytes
+	}
+	a.pings++
+	a.bytes += size
+
+	return &admittedRequest{admission: a, reserved: size, counted: counted}, ""
+}
+
+// reserve adds n bytes read from an admitted body, reporting whether they fit
+// the budget
+func (a *admissionController) reserve(n int64) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.maxBytes > 0 && a.pings > 1 && a.bytes+n > a.maxBytes {
+		return false
+	}
+	a.bytes += n
+	return true
+}
+
+// release frees the slot and bytes of a processed ping
+func (a *admissionController) release(bytes int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pings--
+	a.bytes -= bytes
+}
+
+// admittedRequest holds what admission control reserved for a request. The
+// methods below are no-ops on a nil *admittedRequest so requests can be read
+// whether or not admission control is configured.
+type admittedRequest struct {
+	admission *admissionController
+	reserved  int64
+
+	// counted is set when the body size is only known once it is read
+	counted bool
+}
+
+// reader returns the reader of the decompressed body, reserving its bytes as
+// they are read unless they were reserved on admission
+func (a *admittedRequest) reader(r io.Reader) io.Reader {
+	if a == nil || !a.counted {
+		return r
+	}
+	return &admittedReader{Reader: r, admitted: a}
+}
+
+// release frees the slot and bytes of the request once its ping is processed
+func (a *admittedRequest) release() {
+	if a == nil {
+		return
+	}
+	a.admission.release(a.reserved)
+}
+
+// admittedReader reserves the bytes of a body as they are read
+type admittedReader struct {
+	io.Reader
+	admitted *admittedRequest
+}
+
+func (r *admittedReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if n > 0 {
+		if !r.admitted.admission.reserve(int64(n)) {
+			return n, errInFlightBytesExceeded
+		}
+		r.admitted.reserved += int64(n)
+	}
+	return n, err
+}
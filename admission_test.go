@@ -0,0 +1,254 @@
+package gleanreceiver
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+)
+
+// fakeMemoryLimiter is an extension reporting memory pressure on demand
+type fakeMemoryLimiter struct {
+	component.StartFunc
+	component.ShutdownFunc
+	refuse atomic.Bool
+}
+
+func (m *fakeMemoryLimiter) MustRefuse() bool {
+	return m.refuse.Load()
+}
+
+// extensionsHost is a host exposing the given extensions
+type extensionsHost struct {
+	extensions map[component.ID]component.Component
+}
+
+func (h extensionsHost) GetExtensions() map[component.ID]component.Component {
+	return h.extensions
+}
+
+func TestNewAdmissionController(t *testing.T) {
+	assert.Nil(t, newAdmissionController(AdmissionConfig{}))
+	assert.NotNil(t, newAdmissionController(AdmissionConfig{MaxInFlightPings: 1}))
+
+	id := component.MustNewID("memory_limiter")
+	assert.NotNil(t, newAdmissionController(AdmissionConfig{MemoryLimiter: &id}))
+}
+
+func TestAdmissionControllerLimits(t *testing.T) {
+	admission := newAdmissionController(AdmissionConfig{MaxInFlightPings: 2, MaxInFlightBytes: 100})
+
+	newRequest := func(size int) *http.Request {
+		return httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("x", size)))
+	}
+
+	// A lone ping is admitted even when larger than the budget
+	large, reason := admission.admit(newRequest(150))
+	require.Empty(t, reason)
+	_, reason = admission.admit(newRequest(10))
+	assert.Equal(t, refusedInFlightBytes, reason)
+	large.release()
+
+	admitted, reason := admission.admit(newRequest(60))
+	require.Empty(t, reason)
+	_, reason = admission.admit(newRequest(50))
+	assert.Equal(t, refusedInFlightBytes, reason)
+	small, reason := admission.admit(newRequest(40))
+	require.Empty(t, reason)
+	_, reason = admission.admit(newRequest(0))
+	assert.Equal(t, refusedInFlightPings, reason)
+
+	admitted.release()
+	small.release()
+	assert.Zero(t, admission.pings)
+	assert.Zero(t, admission.bytes)
+}
+
+func TestAdmissionControllerUnknownLength(t *testing.T) {
+	admission := newAdmissionController(AdmissionConfig{MaxInFlightBytes: 100})
+
+	admitted, reason := admission.admit(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("x", 60))))
+	require.Empty(t, reason)
+	defer admitted.release()
+
+	req := httptest.NewRequest(http.MethodPost, "/", io.MultiReader(strings.NewReader(strings.Repeat("x", 60))))
+	req.ContentLength = -1
+	unknown, reason := admission.admit(req)
+	require.Empty(t, reason)
+
+	// The body is only refused once its bytes cross the budget
+	_, err := io.ReadAll(unknown.reader(req.Body))
+	assert.ErrorIs(t, err, errInFlightBytesExceeded)
+	unknown.release()
+	assert.Equal(t, int64(60), admission.bytes)
+}
+
+func TestAdmissionControllerCompressedBody(t *testing.T) {
+	admission := newAdmissionController(AdmissionConfig{MaxInFlightBytes: 100})
+
+	admitted, reason := admission.admit(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("x", 60))))
+	require.Empty(t, reason)
+
+	// The compressed size is far below the budget, the decompressed one is not
+	compressed := gzipBytes(t, []byte(strings.Repeat("x", 60)))
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(compressed))
+	req.Header.Set("Content-Encoding", "gzip")
+	gzipped, reason := admission.admit(req)
+	require.Empty(t, reason)
+	assert.Equal(t, int64(60), admission.bytes)
+
+	_, err := readRequestBody(req, 1000, gzipped)
+	assert.ErrorIs(t, err, errInFlightBytesExceeded)
+	gzipped.release()
+	assert.Equal(t, int64(60), admission.bytes)
+
+	// Alone, the decompressed bytes are reserved while the ping is processed
+	admitted.release()
+	req = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(compressed))
+	req.Header.Set("Content-Encoding", "gzip")
+	gzipped, reason = admission.admit(req)
+	require.Empty(t, reason)
+	body, err := readRequestBody(req, 1000, gzipped)
+	require.NoError(t, err)
+	assert.Len(t, body, 60)
+	assert.Equal(t, int64(60), admission.bytes)
+	gzipped.release()
+	assert.Zero(t, admission.bytes)
+}
+
+func TestAdmissionControllerMemoryLimiter(t *testing.T) {
+	id := component.MustNewID("memory_limiter")
+	limiter := &fakeMemoryLimiter{}
+	admission := newAdmissionController(AdmissionConfig{MemoryLimiter: &id})
+
+	err := admission.resolveMemoryLimiter(componenttest.NewNopHost(), id)
+	assert.Error(t, err)
+	err = admission.resolveMemoryLimiter(extensionsHost{map[component.ID]component.Component{
+		id: struct {
+			component.StartFunc
+			component.ShutdownFunc
+		}{},
+	}}, id)
+	assert.Error(t, err)
+
+	err = admission.resolveMemoryLimiter(extensionsHost{map[component.ID]component.Component{id: limiter}}, id)
+	require.NoError(t, err)
+
+	admitted, reason := admission.admit(httptest.NewRequest(http.MethodPost, "/", nil))
+	require.Empty(t, reason)
+	admitted.release()
+
+	limiter.refuse.Store(true)
+	_, reason = admission.admit(httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, refusedMemoryLimiter, reason)
+}
+
+func TestReceiverAdmissionControl(t *testing.T) {
+	id := component.MustNewID("memory_limiter")
+	limiter := &fakeMemoryLimiter{}
+
+	cfg := &Config{
+		Path:       "/test",
+		RetryAfter: 5 * time.Second,
+		Admission: AdmissionConfig{
+			MaxInFlightPings: 1,
+			MemoryLimiter:    &id,
+		},
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19917"
+
+	// The pipeline holds the first ping until unblocked
+	consuming := make(chan struct{}, 10)
+	unblock := make(chan struct{})
+	metricsConsumer, err := consumer.NewMetrics(func(context.Context, pmetric.Metrics) error {
+		consuming <- struct{}{}
+		<-unblock
+		return nil
+	})
+	require.NoError(t, err)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		metricsConsumer,
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = receiver.Start(ctx, extensionsHost{map[component.ID]component.Component{id: limiter}})
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	const url = "http://localhost:19917/test/test-app/metrics/1/doc-1"
+
+	firstStatus := make(chan int)
+	go func() {
+		resp, err := http.Post(url, "application/json", bytes.NewBufferString(envelopePingBody))
+		if err != nil {
+			firstStatus <- 0
+			return
+		}
+		resp.Body.Close()
+		firstStatus <- resp.StatusCode
+	}()
+	<-consuming
+
+	resp, err := http.Post(url, "application/json", bytes.NewBufferString(envelopePingBody))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "5", resp.Header.Get("Retry-After"))
+
+	close(unblock)
+	assert.Equal(t, http.StatusOK, <-firstStatus)
+
+	// Memory pressure refuses pings while the receiver is idle
+	limiter.refuse.Store(true)
+	resp, err = http.Post(url, "application/json", bytes.NewBufferString(envelopePingBody))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	limiter.refuse.Store(false)
+	resp, err = http.Post(url, "application/json", bytes.NewBufferString(envelopePingBody))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestReceiverMissingMemoryLimiter(t *testing.T) {
+	id := component.MustNewID("memory_limiter")
+	cfg := &Config{
+		Path:      "/test",
+		Admission: AdmissionConfig{MemoryLimiter: &id},
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19918"
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		consumertest.NewNop(),
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	err = receiver.Start(context.Background(), componenttest.NewNopHost())
+	assert.Error(t, err)
+}
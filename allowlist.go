@@ -0,0 +1,121 @@
+package gleanreceiver
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Reasons a ping is refused, used as the reason attribute of the refused pings counter
+const (
+	refusedNamespaceNotAllowed       = "namespace_not_allowed"
+	refusedDocumentTypeNotAllowed    = "document_type_not_allowed"
+	refusedDocumentVersionNotAllowed = "document_version_not_allowed"
+)
+
+// versionRange is an inclusive range of document versions
+type versionRange struct {
+	min int
+	max int
+}
+
+// pingAllowlist restricts the namespaces, document types and document
+// versions accepted by the receiver
+type pingAllowlist struct {
+	namespaces    []string
+	documentTypes []string
+	versions      []versionRange
+}
+
+// newPingAllowlist creates a new instance of pingAllowlist. It returns nil
+// when nothing is restricted.
+func newPingAllowlist(cfg AllowlistConfig) (*pingAllowlist, error) {
+	if len(cfg.Namespaces) == 0 && len(cfg.DocumentTypes) == 0 && len(cfg.DocumentVersions) == 0 {
+		return nil, nil
+	}
+
+	for _, pattern := range slices.Concat(cfg.Namespaces, cfg.DocumentTypes) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid allowlist pattern %q: %w", pattern, err)
+		}
+	}
+
+	versions := make([]versionRange, 0, len(cfg.DocumentVersions))
+	for _, version := range cfg.DocumentVersions {
+		r, err := parseVersionRange(version)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, r)
+	}
+
+	return &pingAllowlist{
+		namespaces:    cfg.Namespaces,
+		documentTypes: cfg.DocumentTypes,
+		versions:      versions,
+	}, nil
+}
+
+// parseVersionRange parses a single version ("1") or an inclusive range ("1-3")
+func parseVersionRange(version string) (versionRange, error) {
+	low, high, isRange := strings.Cut(version, "-")
+	if !isRange {
+		high = low
+	}
+
+	minVersion, err := strconv.Atoi(strings.TrimSpace(low))
+	if err != nil {
+		return versionRange{}, fmt.Errorf("invalid document version %q", version)
+	}
+	maxVersion, err := strconv.Atoi(strings.TrimSpace(high))
+	if err != nil || maxVersion < minVersion {
+		return versionRange{}, fmt.Errorf("invalid document version range %q", version)
+	}
+
+	return versionRange{min: minVersion, max: maxVersion}, nil
+}
+
+// check returns the reason the ping is not allowed, or an empty string.
+// Deletion-request pings are only checked against the namespaces: a refused
+// deletion request would never be purged, but one for a namespace the
+// receiver refuses cannot match any state it holds.
+func (a *pingAllowlist) check(gleanRequest GleanPingRequest) string {
+	if len(a.namespaces) > 0 && !matchesAny(a.namespaces, gleanRequest.Namespace) {
+		return refusedNamespaceNotAllowed
+	}
+	if gleanRequest.DocumentType == deletionRequestDocumentType {
+		return ""
+	}
+	if len(a.documentTypes) > 0 && !matchesAny(a.documentTypes, gleanRequest.DocumentType) {
+		return refusedDocumentTypeNotAllowed
+	}
+	if len(a.versions) > 0 {
+		version, err := strconv.Atoi(gleanRequest.DocumentVersion)
+		if err != nil {
+			return refusedDocumentVersionNotAllowed
+		}
+		allowed := false
+		for _, r := range a.versions {
+			if version >= r.min && version <= r.max {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return refusedDocumentVersionNotAllowed
+		}
+	}
+	return ""
+}
+
+// matchesAny reports whether value matches any of the glob patterns
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package gleanreceiver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPingAllowlistCheck(t *testing.T) {
+	allowlist, err := newPingAllowlist(AllowlistConfig{
+		Namespaces:       []string{"org-mozilla-*", "glean"},
+		DocumentTypes:    []string{"metrics", "events"},
+		DocumentVersions: []string{"1", "3-4"},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, allowlist)
+
+	tests := []struct {
+		name     string
+		request  GleanPingRequest
+		expected string
+	}{
+		{"allowed", GleanPingRequest{Namespace: "org-mozilla-fenix", DocumentType: "metrics", DocumentVersion: "1"}, ""},
+		{"allowed exact namespace", GleanPingRequest{Namespace: "glean", DocumentType: "events", DocumentVersion: "4"}, ""},
+		{"namespace", GleanPingRequest{Namespace: "unknown", DocumentType: "metrics", DocumentVersion: "1"}, refusedNamespaceNotAllowed},
+		{"document type", GleanPingRequest{Namespace: "glean", DocumentType: "baseline", DocumentVersion: "1"}, refusedDocumentTypeNotAllowed},
+		{"version outside range", GleanPingRequest{Namespace: "glean", DocumentType: "metrics", DocumentVersion: "2"}, refusedDocumentVersionNotAllowed},
+		{"non-numeric version", GleanPingRequest{Namespace: "glean", DocumentType: "metrics", DocumentVersion: "v1"}, refusedDocumentVersionNotAllowed},
+		{"deletion request", GleanPingRequest{Namespace: "glean", DocumentType: "deletion-request", DocumentVersion: "2"}, ""},
+		{"deletion request namespace", GleanPingRequest{Namespace: "unknown", DocumentType: "deletion-request", DocumentVersion: "1"}, refusedNamespaceNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, allowlist.check(tt.request))
+		})
+	}
+}
+
+func TestNewPingAllowlist(t *testing.T) {
+	allowlist, err := newPingAllowlist(AllowlistConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, allowlist, "empty allowlist accepts everything")
+
+	_, err = newPingAllowlist(AllowlistConfig{Namespaces: []string{"[unclosed"}})
+	assert.Error(t, err)
+
+	_, err = newPingAllowlist(AllowlistConfig{DocumentVersions: []string{"3-1"}})
+	assert.Error(t, err)
+
+	_, err = newPingAllowlist(AllowlistConfig{DocumentVersions: []string{"latest"}})
+	assert.Error(t, err)
+}
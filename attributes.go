@@ -0,0 +1,342 @@
+package gleanreceiver
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// Attribute mapping presets
+const (
+	attributePresetLegacy  = "legacy"
+	attributePresetSemconv = "semconv"
+)
+
+// Levels a Glean field can be exported at
+const (
+	attributeLevelResource  = "resource"
+	attributeLevelScope     = "scope"
+	attributeLevelDataPoint = "datapoint"
+
+	// attributeLevelPingInfo is where each signal put ping_info fields
+	// before mapping was configurable: the scope for metrics and traces and
+	// the resource for logs. It cannot be configured.
+	attributeLevelPingInfo = ""
+)
+
+// attributeTarget is where a client_info or ping_info field is exported.
+// An empty name drops the field.
+type attributeTarget struct {
+	name  string
+	level string
+}
+
+// legacyAttributeTargets are the attribute names used before mapping was
+// configurable. Fields whose name ends in ".ext" or is "experiments" are
+// prefixes for a set of attributes.
+var legacyAttributeTargets = map[string]attributeTarget{
+	"client_id":            {"client.id", attributeLevelResource},
+	"session_id":           {"session.id", attributeLevelResource},
+	"session_count":        {"session.count", attributeLevelResource},
+	"app_build":            {"service.version", attributeLevelResource},
+	"app_display_version":  {"app.version", attributeLevelResource},
+	"app_channel":          {"app.channel", attributeLevelResource},
+	"telemetry_sdk_build":  {"telemetry.sdk.version", attributeLevelResource},
+	"architecture":         {"host.arch", attributeLevelResource},
+	"device_manufacturer":  {"device.manufacturer", attributeLevelResource},
+	"device_model":         {"device.model.name", attributeLevelResource},
+	"os":                   {"os.type", attributeLevelResource},
+	"os_version":           {"os.version", attributeLevelResource},
+	"android_sdk_version":  {"android.os.api_level", attributeLevelResource},
+	"windows_build_number": {"os.build_id", attributeLevelResource},
+	"locale":               {"host.locale", attributeLevelResource},
+	"build_date":           {"app.build_date", attributeLevelResource},
+	"first_run_date":       {"app.first_run_date", attributeLevelResource},
+	"attribution.campaign": {"app.attribution.campaign", attributeLevelResource},
+	"attribution.content":  {"app.attribution.content", attributeLevelResource},
+	"attribution.medium":   {"app.attribution.medium", attributeLevelResource},
+	"attribution.source":   {"app.attribution.source", attributeLevelResource},
+	"attribution.term":     {"app.attribution.term", attributeLevelResource},
+	"attribution.ext":      {"app.attribution.ext", attributeLevelResource},
+	"distribution.name":    {"app.distribution.name", attributeLevelResource},
+	"distribution.ext":     {"app.distribution.ext", attributeLevelResource},
+	"seq":                  {"ping.seq", attributeLevelPingInfo},
+	"ping_type":            {"ping.type", attributeLevelPingInfo},
+	"reason":               {"ping.reason", attributeLevelPingInfo},
+	"experiments":          {"experiment", attributeLevelPingInfo},
+}
+
+// semconvAttributeOverrides are the fields the semconv preset exports
+// differently from the legacy preset. Names outside the semantic conventions
+// that squat on a semconv namespace are moved under glean.*.
+var semconvAttributeOverrides = map[string]attributeTarget{
+	"client_id":           {"glean.client_id", attributeLevelResource},
+	"app_build":           {"app.build_id", attributeLevelResource},
+	"app_display_version": {"service.version", attributeLevelResource},
+	"device_model":        {"device.model.identifier", attributeLevelResource},
+	"os":                  {"os.name", attributeLevelResource},
+	"locale":              {"glean.locale", attributeLevelResource},
+}
+
+// attributeMapper maps client_info and ping_info fields to attributes. A nil
+// mapper uses the legacy preset.
+type attributeMapper struct {
+	targets map[string]attributeTarget
+
+	// serviceNames maps Glean application IDs to service.name values
+	serviceNames map[string]string
+
+	// requestHeaders are the HTTP headers exported as attributes
+	requestHeaders []string
+}
+
+// newAttributeMapper builds the mapping from the attribute preset and
+// per-field overrides, service names and request headers in cfg
+func newAttributeMapper(receiverCfg *Config) (*attributeMapper, error) {
+	cfg := receiverCfg.Attributes
+	targets := make(map[string]attributeTarget, len(legacyAttributeTargets))
+	for field, target := range legacyAttributeTargets {
+		targets[field] = target
+	}
+
+	switch cfg.Preset {
+	case "", attributePresetLegacy:
+	case attributePresetSemconv:
+		for field, target := range semconvAttributeOverrides {
+			targets[field] = target
+		}
+	default:
+		return nil, fmt.Errorf("invalid attributes.preset %q", cfg.Preset)
+	}
+
+	// Apply overrides in a stable order so errors are deterministic
+	fields := make([]string, 0, len(cfg.Mappings))
+	for field := range cfg.Mappings {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		mapping := cfg.Mappings[field]
+		target, ok := targets[field]
+		if !ok {
+			return nil, fmt.Errorf("unknown attributes.mappings field %q", field)
+		}
+		switch mapping.Level {
+		case "":
+		case attributeLevelResource, attributeLevelScope, attributeLevelDataPoint:
+			target.level = mapping.Level
+		default:
+			return nil, fmt.Errorf("invalid attributes.mappings.%s.level %q", field, mapping.Level)
+		}
+		if mapping.Name != "" {
+			target.name = mapping.Name
+		}
+		if mapping.Drop {
+			target.name = ""
+		}
+		targets[field] = target
+	}
+
+	return &attributeMapper{
+		targets:        targets,
+		serviceNames:   receiverCfg.ServiceNames,
+		requestHeaders: receiverCfg.RequestHeaders,
+	}, nil
+}
+
+// target returns where field is exported
+func (m *attributeMapper) target(field string) attributeTarget {
+	if m == nil {
+		return legacyAttributeTargets[field]
+	}
+	return m.targets[field]
+}
+
+// mappedAttributes collects the attributes of a ping per level. pingInfo
+// holds the fields each signal places itself, see attributeLevelPingInfo.
+// record holds the per-ping identifiers, which only go on log records and
+// spans so metric series do not multiply with every ping.
+type mappedAttributes struct {
+	resource  pcommon.Map
+	scope     pcommon.Map
+	dataPoint pcommon.Map
+	pingInfo  pcommon.Map
+	record    pcommon.Map
+}
+
+func newMappedAttributes() mappedAttributes {
+	return mappedAttributes{
+		resource:  pcommon.NewMap(),
+		scope:     pcommon.NewMap(),
+		dataPoint: pcommon.NewMap(),
+		pingInfo:  pcommon.NewMap(),
+		record:    pcommon.NewMap(),
+	}
+}
+
+// destination returns the map for field, or false if the field is dropped
+func (m *attributeMapper) destination(attrs mappedAttributes, field string) (pcommon.Map, string, bool) {
+	target := m.target(field)
+	if target.name == "" {
+		return pcommon.Map{}, "", false
+	}
+	switch target.level {
+	case attributeLevelScope:
+		return attrs.scope, target.name, true
+	case attributeLevelDataPoint:
+		return attrs.dataPoint, target.name, true
+	case attributeLevelPingInfo:
+		return attrs.pingInfo, target.name, true
+	default:
+		return attrs.resource, target.name, true
+	}
+}
+
+// putStr adds a string field unless value is empty
+func (m *attributeMapper) putStr(attrs mappedAttributes, field string, value string) {
+	if value == "" {
+		return
+	}
+	if dst, name, ok := m.destination(attrs, field); ok {
+		dst.PutStr(name, value)
+	}
+}
+
+// putInt adds an integer field
+func (m *attributeMapper) putInt(attrs mappedAttributes, field string, value int64) {
+	if dst, name, ok := m.destination(attrs, field); ok {
+		dst.PutInt(name, value)
+	}
+}
+
+// putExt adds free-form ext fields as attributes under the field's prefix
+func (m *attributeMapper) putExt(attrs mappedAttributes, field string, ext map[string]any) {
+	if len(ext) == 0 {
+		return
+	}
+	dst, prefix, ok := m.destination(attrs, field)
+	if !ok {
+		return
+	}
+	for k, v := range ext {
+		if err := dst.PutEmpty(prefix + "." + k).FromRaw(v); err != nil {
+			dst.PutStr(prefix+"."+k, fmt.Sprint(v))
+		}
+	}
+}
+
+// mapClientInfo adds client_info fields to attrs
+func (m *attributeMapper) mapClientInfo(attrs mappedAttributes, clientInfo *ClientInfo) {
+	m.putStr(attrs, "client_id", clientInfo.ClientID)
+	m.putStr(attrs, "session_id", clientInfo.SessionID)
+	if clientInfo.SessionCount > 0 {
+		m.putInt(attrs, "session_count", int64(clientInfo.SessionCount))
+	}
+	m.putStr(attrs, "app_build", clientInfo.AppBuild)
+	m.putStr(attrs, "app_display_version", clientInfo.AppDisplayVersion)
+	m.putStr(attrs, "app_channel", clientInfo.AppChannel)
+	m.putStr(attrs, "telemetry_sdk_build", clientInfo.TelemetrySDKBuild)
+	m.putStr(attrs, "architecture", clientInfo.Architecture)
+	m.putStr(attrs, "device_manufacturer", clientInfo.DeviceManufacturer)
+	m.putStr(attrs, "device_model", clientInfo.DeviceModel)
+	m.putStr(attrs, "os", clientInfo.OS)
+	m.putStr(attrs, "os_version", clientInfo.OSVersion)
+	m.putStr(attrs, "android_sdk_version", clientInfo.AndroidSDKVersion)
+	if clientInfo.WindowsBuildNumber > 0 {
+		m.putStr(attrs, "windows_build_number", strconv.Itoa(clientInfo.WindowsBuildNumber))
+	}
+	m.putStr(attrs, "locale", clientInfo.Locale)
+	m.putStr(attrs, "build_date", clientInfo.BuildDate)
+	m.putStr(attrs, "first_run_date", clientInfo.FirstRunDate)
+	if attribution := clientInfo.Attribution; attribution != nil {
+		m.putStr(attrs, "attribution.campaign", attribution.Campaign)
+		m.putStr(attrs, "attribution.content", attribution.Content)
+		m.putStr(attrs, "attribution.medium", attribution.Medium)
+		m.putStr(attrs, "attribution.source", attribution.Source)
+		m.putStr(attrs, "attribution.term", attribution.Term)
+		m.putExt(attrs, "attribution.ext", attribution.Ext)
+	}
+	if distribution := clientInfo.Distribution; distribution != nil {
+		m.putStr(attrs, "distribution.name", distribution.Name)
+		m.putExt(attrs, "distribution.ext", distribution.Ext)
+	}
+}
+
+// mapPingInfo adds ping_info fields to attrs
+func (m *attributeMapper) mapPingInfo(attrs mappedAttributes, pingInfo *PingInfo) {
+	m.putInt(attrs, "seq", int64(pingInfo.Seq))
+	if dst, name, ok := m.destination(attrs, "ping_type"); ok {
+		dst.PutStr(name, pingInfo.PingType)
+	}
+	m.putStr(attrs, "reason", pingInfo.Reason)
+	if dst, prefix, ok := m.destination(attrs, "experiments"); ok {
+		for id, experiment := range pingInfo.Experiments {
+			dst.PutStr(prefix+"."+id+".branch", experiment.Branch)
+		}
+	}
+}
+
+// mapService adds the service resource attributes. service.name is the
+// Glean application ID from the URL namespace, or its configured name, and
+// service.namespace and deployment.environment are the release channel.
+// Explicit attribute mappings take precedence.
+func (m *attributeMapper) mapService(attrs mappedAttributes, namespace string, clientInfo *ClientInfo) {
+	if namespace != "" {
+		serviceName := namespace
+		if m != nil {
+			if name, ok := m.serviceNames[namespace]; ok {
+				serviceName = name
+			}
+		}
+		attrs.resource.PutStr("service.name", serviceName)
+	}
+	if clientInfo.AppChannel != "" {
+		attrs.resource.PutStr("service.namespace", clientInfo.AppChannel)
+		attrs.resource.PutStr("deployment.environment", clientInfo.AppChannel)
+	}
+}
+
+// mapPing returns the client_info and ping_info attributes of ping
+func (m *attributeMapper) mapPing(ping *GleanPing) mappedAttributes {
+	attrs := newMappedAttributes()
+	m.mapService(attrs, ping.Request.Namespace, &ping.ClientInfo)
+	m.mapRequest(attrs, &ping.Request)
+	m.mapClientInfo(attrs, &ping.ClientInfo)
+	m.mapPingInfo(attrs, &ping.PingInfo)
+	return attrs
+}
+
+// mergeAttributes copies every attribute of src into dst, keeping the other
+// attributes of dst
+func mergeAttributes(dst pcommon.Map, src pcommon.Map) {
+	src.Range(func(k string, v pcommon.Value) bool {
+		v.CopyTo(dst.PutEmpty(k))
+		return true
+	})
+}
+
+// forEachDataPointAttributes calls fn with the attributes of every data point
+// of the metrics starting at index first
+func forEachDataPointAttributes(metrics pmetric.MetricSlice, first int, fn func(pcommon.Map)) {
+	for i := first; i < metrics.Len(); i++ {
+		metric := metrics.At(i)
+		switch metric.Type() {
+		case pmetric.MetricTypeGauge:
+			for j := 0; j < metric.Gauge().DataPoints().Len(); j++ {
+				fn(metric.Gauge().DataPoints().At(j).Attributes())
+			}
+		case pmetric.MetricTypeSum:
+			for j := 0; j < metric.Sum().DataPoints().Len(); j++ {
+				fn(metric.Sum().DataPoints().At(j).Attributes())
+			}
+		case pmetric.MetricTypeHistogram:
+			for j := 0; j < metric.Histogram().DataPoints().Len(); j++ {
+				fn(metric.Histogram().DataPoints().At(j).Attributes())
+			}
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package gleanreceiver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+func newTestAttributePing() *GleanPing {
+	return &GleanPing{
+		ClientInfo: ClientInfo{
+			ClientID:          "test-client-id",
+			AppBuild:          "1024",
+			AppDisplayVersion: "1.2.3",
+			OS:                "Android",
+			Locale:            "en-US",
+			Attribution:       &ClientAttribution{Ext: map[string]any{"campaign_id": "42"}},
+		},
+		PingInfo: PingInfo{
+			Seq:       7,
+			StartTime: time.Date(2024, 1, 28, 10, 0, 0, 0, time.UTC),
+			EndTime:   time.Date(2024, 1, 28, 10, 1, 0, 0, time.UTC),
+			PingType:  "metrics",
+			Reason:    "scheduled",
+			Experiments: map[string]Experiment{
+				"dark-mode": {Branch: "control"},
+			},
+		},
+	}
+}
+
+func TestAttributeMapperLegacy(t *testing.T) {
+	// A nil mapper and the legacy preset produce the same attributes
+	mapper, err := newAttributeMapper(&Config{Attributes: AttributesConfig{Preset: attributePresetLegacy}})
+	require.NoError(t, err)
+
+	for _, m := range []*attributeMapper{nil, mapper} {
+		attrs := m.mapPing(newTestAttributePing())
+		assert.Equal(t, map[string]any{
+			"client.id":                       "test-client-id",
+			"service.version":                 "1024",
+			"app.version":                     "1.2.3",
+			"os.type":                         "Android",
+			"host.locale":                     "en-US",
+			"app.attribution.ext.campaign_id": "42",
+		}, attrs.resource.AsRaw())
+		// ping_info is placed by each signal
+		assert.Equal(t, map[string]any{
+			"ping.seq":                    int64(7),
+			"ping.type":                   "metrics",
+			"ping.reason":                 "scheduled",
+			"experiment.dark-mode.branch": "control",
+		}, attrs.pingInfo.AsRaw())
+		assert.Equal(t, 0, attrs.scope.Len())
+		assert.Equal(t, 0, attrs.dataPoint.Len())
+	}
+}
+
+func TestAttributeMapperSemconv(t *testing.T) {
+	mapper, err := newAttributeMapper(&Config{Attributes: AttributesConfig{Preset: attributePresetSemconv}})
+	require.NoError(t, err)
+
+	attrs := mapper.mapPing(newTestAttributePing())
+	assert.Equal(t, map[string]any{
+		"glean.client_id":                 "test-client-id",
+		"app.build_id":                    "1024",
+		"service.version":                 "1.2.3",
+		"os.name":                         "Android",
+		"glean.locale":                    "en-US",
+		"app.attribution.ext.campaign_id": "42",
+	}, attrs.resource.AsRaw())
+}
+
+func TestAttributeMapperOverrides(t *testing.T) {
+	mapper, err := newAttributeMapper(&Config{Attributes: AttributesConfig{
+		Mappings: map[string]AttributeMapping{
+			"locale":          {Drop: true},
+			"app_build":       {Name: "app.build_id"},
+			"reason":          {Level: attributeLevelDataPoint},
+			"client_id":       {Name: "user.id", Level: attributeLevelScope},
+			"attribution.ext": {Name: "attribution"},
+		},
+	}})
+	require.NoError(t, err)
+
+	attrs := mapper.mapPing(newTestAttributePing())
+	assert.Equal(t, map[string]any{
+		"app.build_id":            "1024",
+		"app.version":             "1.2.3",
+		"os.type":                 "Android",
+		"attribution.campaign_id": "42",
+	}, attrs.resource.AsRaw())
+
+	userID, exists := attrs.scope.Get("user.id")
+	assert.True(t, exists)
+	assert.Equal(t, "test-client-id", userID.Str())
+	_, exists = attrs.scope.Get("ping.reason")
+	assert.False(t, exists)
+
+	assert.Equal(t, map[string]any{"ping.reason": "scheduled"}, attrs.dataPoint.AsRaw())
+}
+
+func TestNewAttributeMapperErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  AttributesConfig
+	}{
+		{
+			name: "unknown preset",
+			cfg:  AttributesConfig{Preset: "ecs"},
+		},
+		{
+			name: "unknown field",
+			cfg:  AttributesConfig{Mappings: map[string]AttributeMapping{"device_color": {Name: "device.color"}}},
+		},
+		{
+			name: "unknown level",
+			cfg:  AttributesConfig{Mappings: map[string]AttributeMapping{"locale": {Level: "span"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newAttributeMapper(&Config{Attributes: tt.cfg})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLegacyAttributePlacementInConversions(t *testing.T) {
+	ping := newTestAttributePing()
+	ping.Metrics = map[string]any{
+		"counter": map[string]any{"app.opened": float64(3)},
+	}
+	ping.Events = []Event{{Timestamp: 0, Category: "ui", Name: "click"}}
+
+	clientInfo := map[string]any{
+		"client.id":                       "test-client-id",
+		"service.version":                 "1024",
+		"app.version":                     "1.2.3",
+		"os.type":                         "Android",
+		"host.locale":                     "en-US",
+		"app.attribution.ext.campaign_id": "42",
+	}
+	pingInfo := map[string]any{
+		"ping.seq":                    int64(7),
+		"ping.type":                   "metrics",
+		"ping.reason":                 "scheduled",
+		"experiment.dark-mode.branch": "control",
+	}
+
+	// Metrics and traces carry ping_info on the scope
+	metrics, err := convertToMetrics(ping, converterSettings{})
+	require.NoError(t, err)
+	rm := metrics.ResourceMetrics().At(0)
+	assert.Equal(t, clientInfo, rm.Resource().Attributes().AsRaw())
+	assert.Equal(t, pingInfo, rm.ScopeMetrics().At(0).Scope().Attributes().AsRaw())
+
+	traces, err := convertToTraces(ping, converterSettings{})
+	require.NoError(t, err)
+	rs := traces.ResourceSpans().At(0)
+	assert.Equal(t, clientInfo, rs.Resource().Attributes().AsRaw())
+	assert.Equal(t, pingInfo, rs.ScopeSpans().At(0).Scope().Attributes().AsRaw())
+
+	// Event logs carry ping_info on the resource
+	logs, err := convertToEventLogs(ping, converterSettings{})
+	require.NoError(t, err)
+	rl := logs.ResourceLogs().At(0)
+	resource := make(map[string]any, len(clientInfo)+len(pingInfo))
+	for k, v := range clientInfo {
+		resource[k] = v
+	}
+	for k, v := range pingInfo {
+		resource[k] = v
+	}
+	assert.Equal(t, resource, rl.Resource().Attributes().AsRaw())
+	assert.Equal(t, 0, rl.ScopeLogs().At(0).Scope().Attributes().Len())
+}
+
+func TestDataPointAttributesInConversions(t *testing.T) {
+	mapper, err := newAttributeMapper(&Config{Attributes: AttributesConfig{
+		Mappings: map[string]AttributeMapping{
+			"ping_type": {Level: attributeLevelDataPoint},
+		},
+	}})
+	require.NoError(t, err)
+	settings := converterSettings{attributes: mapper}
+
+	ping := newTestAttributePing()
+	ping.Metrics = map[string]any{
+		"counter":  map[string]any{"app.opened": float64(3)},
+		"quantity": map[string]any{"app.memory": float64(256)},
+		"timespan": map[string]any{"app.startup": map[string]any{"value": float64(120), "time_unit": "millisecond"}},
+	}
+	ping.Events = []Event{{Timestamp: 0, Category: "ui", Name: "click"}}
+
+	hasPingType := func(t *testing.T, attrs pcommon.Map) {
+		value, exists := attrs.Get("ping.type")
+		assert.True(t, exists)
+		assert.Equal(t, "metrics", value.Str())
+	}
+
+	metrics, err := convertToMetrics(ping, settings)
+	require.NoError(t, err)
+	scopeMetrics := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+	_, exists := scopeMetrics.Scope().Attributes().Get("ping.type")
+	assert.False(t, exists)
+	count := 0
+	forEachDataPointAttributes(scopeMetrics.Metrics(), 0, func(attrs pcommon.Map) {
+		hasPingType(t, attrs)
+		count++
+	})
+	// Both metrics and the experiment enrollment data point
+	assert.Equal(t, 3, count)
+
+	logs, err := convertToEventLogs(ping, settings)
+	require.NoError(t, err)
+	hasPingType(t, logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Attributes())
+
+	traces, err := convertToTraces(ping, settings)
+	require.NoError(t, err)
+	spans := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	require.Equal(t, 2, spans.Len())
+	for i := 0; i < spans.Len(); i++ {
+		hasPingType(t, spans.At(i).Attributes())
+	}
+}
+
+func TestAttributeMapperService(t *testing.T) {
+	mapper, err := newAttributeMapper(&Config{
+		ServiceNames: map[string]string{"org-mozilla-fenix": "firefox-android"},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		mapper    *attributeMapper
+		namespace string
+		expected  map[string]any
+	}{
+		{
+			name:      "application ID",
+			mapper:    nil,
+			namespace: "org-mozilla-focus",
+			expected: map[string]any{
+				"service.name":           "org-mozilla-focus",
+				"service.namespace":      "beta",
+				"deployment.environment": "beta",
+			},
+		},
+		{
+			name:      "configured name",
+			mapper:    mapper,
+			namespace: "org-mozilla-fenix",
+			expected: map[string]any{
+				"service.name":           "firefox-android",
+				"service.namespace":      "beta",
+				"deployment.environment": "beta",
+			},
+		},
+		{
+			name:   "no namespace",
+			mapper: mapper,
+			expected: map[string]any{
+				"service.namespace":      "beta",
+				"deployment.environment": "beta",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := newMappedAttributes()
+			tt.mapper.mapService(attrs, tt.namespace, &ClientInfo{AppChannel: "beta"})
+			assert.Equal(t, tt.expected, attrs.resource.AsRaw())
+		})
+	}
+}
+
+func TestServiceNameInConversions(t *testing.T) {
+	ping := newTestAttributePing()
+	ping.Request = GleanPingRequest{Namespace: "org-mozilla-fenix", DocumentType: "metrics"}
+
+	hasServiceName := func(t *testing.T, attrs pcommon.Map) {
+		value, exists := attrs.Get("service.name")
+		assert.True(t, exists)
+		assert.Equal(t, "org-mozilla-fenix", value.Str())
+	}
+
+	metrics, err := convertToMetrics(ping, converterSettings{})
+	require.NoError(t, err)
+	hasServiceName(t, metrics.ResourceMetrics().At(0).Resource().Attributes())
+
+	logs, err := convertToEventLogs(ping, converterSettings{})
+	require.NoError(t, err)
+	hasServiceName(t, logs.ResourceLogs().At(0).Resource().Attributes())
+
+	traces, err := convertToTraces(ping, converterSettings{})
+	require.NoError(t, err)
+	hasServiceName(t, traces.ResourceSpans().At(0).Resource().Attributes())
+
+	deletion, err := convertToDeletionLog(ping, converterSettings{})
+	require.NoError(t, err)
+	hasServiceName(t, deletion.ResourceLogs().At(0).Resource().Attributes())
+
+	validation, err := convertToValidationErrorLog(ping.Request, nil, converterSettings{})
+	require.NoError(t, err)
+	hasServiceName(t, validation.ResourceLogs().At(0).Resource().Attributes())
+}
@@ -2,11 +2,19 @@ package gleanreceiver
 
 import (
 	"errors"
+	"fmt"
 	"path"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configgrpc"
 	"go.opentelemetry.io/collector/config/confighttp"
+	"go.opentelemetry.io/collector/config/confignet"
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/config/configoptional"
 )
 
 // Config defines the configuration for the Glean receiver
@@ -14,6 +22,29 @@ type Config struct {
 	// ServerConfig contains HTTP server settings
 	confighttp.ServerConfig `mapstructure:",squash"`
 
+	// Routes are further submission paths served next to Path, such as
+	// legacy Firefox telemetry or short internal routes
+	Routes []RouteConfig `mapstructure:"routes"`
+
+	// AdditionalEndpoints are further addresses serving the same handlers as
+	// the main endpoint, such as a Unix domain socket shared with a sidecar
+	AdditionalEndpoints []confignet.AddrConfig `mapstructure:"additional_endpoints"`
+
+	// Admission bounds the pings the HTTP server processes at once
+	Admission AdmissionConfig `mapstructure:"admission"`
+
+	// RateLimit throttles HTTP pings per client IP, client_id or namespace
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+
+	// Limits bounds the size of pings and what is converted from them
+	Limits LimitsConfig `mapstructure:"limits"`
+
+	// RetryAfter is sent as the Retry-After header of 503 responses, telling
+	// Glean SDKs when to retry a ping the pipeline could not accept. If 0,
+	// the header is omitted.
+	// Default: 60s
+	RetryAfter time.Duration `mapstructure:"retry_after"`
+
 	// Path is the HTTP path where Glean pings are received
 	// Default: /submit/telemetry
 	Path string `mapstructure:"path"`
@@ -28,6 +59,440 @@ type Config struct {
 	// ForwardTimeout is the HTTP client timeout for forwarding requests
 	// Default: 30s
 	ForwardTimeout time.Duration `mapstructure:"forward_timeout"`
+
+	// Sessions configures stitching events from pings that share a
+	// client_info.session_id into a single trace
+	Sessions SessionsConfig `mapstructure:"sessions"`
+
+	// Privacy configures how persistent client identifiers are exported
+	Privacy PrivacyConfig `mapstructure:"privacy"`
+
+	// DeletionRequest configures handling of deletion-request pings
+	DeletionRequest DeletionRequestConfig `mapstructure:"deletion_request"`
+
+	// Redaction configures removal of personal data from string, text and
+	// url metrics and event extras
+	Redaction RedactionConfig `mapstructure:"redaction"`
+
+	// Registry configures the Glean metrics.yaml and pings.yaml definitions
+	// used for typed conversion
+	Registry RegistryConfig `mapstructure:"registry"`
+
+	// SchemaValidation configures validation of pings against the Glean
+	// ping JSON schema before conversion
+	SchemaValidation SchemaValidationConfig `mapstructure:"schema_validation"`
+
+	// Allowlist restricts which namespaces, document types and document
+	// versions are accepted
+	Allowlist AllowlistConfig `mapstructure:"allowlist"`
+
+	// Attributes configures how client_info and ping_info fields are
+	// exported as attributes
+	Attributes AttributesConfig `mapstructure:"attributes"`
+
+	// ServiceNames maps Glean application IDs ({namespace}) to the
+	// service.name exported for them. Unlisted IDs are used as-is.
+	ServiceNames map[string]string `mapstructure:"service_names"`
+
+	// RequestHeaders are the HTTP request headers exported as resource
+	// attributes and client metadata
+	// Default: X-Debug-ID, X-Source-Tags, X-Telemetry-Agent, User-Agent
+	RequestHeaders []string `mapstructure:"request_headers"`
+
+	// Debug configures the local debug view of pings tagged with X-Debug-ID
+	Debug DebugConfig `mapstructure:"debug"`
+
+	// SourceTags configures how pings are handled based on their
+	// X-Source-Tags header
+	SourceTags SourceTagsConfig `mapstructure:"source_tags"`
+
+	// PendingPings configures ingesting pings from Glean SDK pending_pings
+	// directories
+	PendingPings PendingPingsConfig `mapstructure:"pending_pings"`
+
+	// Replay configures replaying NDJSON archives of raw pings on start
+	Replay ReplayConfig `mapstructure:"replay"`
+
+	// Kafka configures consuming ping envelopes from Kafka topics
+	Kafka KafkaConfig `mapstructure:"kafka"`
+
+	// GRPC configures the gRPC ingestion API defined in gleanpb/ingest.proto.
+	// The API is only served when this section is present.
+	GRPC configoptional.Optional[configgrpc.ServerConfig] `mapstructure:"grpc"`
+}
+
+// RouteConfig defines a submission path and how it maps onto a ping request
+type RouteConfig struct {
+	// Path is a ServeMux pattern such as /glean/{document_type}. The
+	// namespace, document_type, document_version and document_id wildcards
+	// fill the ping request, other wildcards are matched but ignored.
+	Path string `mapstructure:"path"`
+
+	// Namespace is used when the path does not capture the namespace
+	Namespace string `mapstructure:"namespace"`
+
+	// DocumentType is used when the path does not capture the document type
+	DocumentType string `mapstructure:"document_type"`
+
+	// DocumentVersion is used when the path does not capture the document
+	// version
+	DocumentVersion string `mapstructure:"document_version"`
+}
+
+// AdmissionConfig defines when HTTP pings are refused with 503 Service
+// Unavailable before their body is read, so clients back off while the
+// pipeline is slow. It only applies to the HTTP endpoint; pings from gRPC,
+// Kafka, pending_pings directories and replayed archives are not admitted
+// through it.
+type AdmissionConfig struct {
+	// MaxInFlightPings is how many pings are processed at once. If 0, the
+	// number is not limited.
+	MaxInFlightPings int `mapstructure:"max_in_flight_pings"`
+
+	// MaxInFlightBytes is how many decompressed request body bytes are
+	// buffered at once. If 0, the size is not limited.
+	MaxInFlightBytes int64 `mapstructure:"max_in_flight_bytes"`
+
+	// MemoryLimiter is the ID of a memory_limiter extension. Pings are
+	// refused while it reports memory pressure.
+	MemoryLimiter *component.ID `mapstructure:"memory_limiter"`
+}
+
+// RateLimitConfig defines the token buckets HTTP pings are throttled with.
+// Throttled pings are answered with 429 Too Many Requests. Only the HTTP
+// endpoint is rate limited; pings from gRPC, Kafka, pending_pings
+// directories and replayed archives are never throttled.
+type RateLimitConfig struct {
+	// Rules are the limits applied. If empty, rate limiting is disabled.
+	Rules []RateLimitRule `mapstructure:"rules"`
+
+	// ClientIPHeader is a header such as X-Forwarded-For that proxies in
+	// front of the receiver append the client IP to. If empty or missing,
+	// the connection's remote address is used.
+	ClientIPHeader string `mapstructure:"client_ip_header"`
+
+	// TrustedProxies is how many proxies in front of the receiver append to
+	// ClientIPHeader. The client IP is the entry the outermost of them
+	// appended, counted from the right, since entries further left are
+	// whatever the client sent. If the header has fewer entries, the
+	// connection's remote address is used.
+	// Default: 1
+	TrustedProxies int `mapstructure:"trusted_proxies"`
+
+	// MaxKeys is how many key values each rule tracks. The least recently
+	// seen value is evicted first.
+	// Default: 10000
+	MaxKeys int `mapstructure:"max_keys"`
+}
+
+// RateLimitRule defines a token bucket kept per value of a key
+type RateLimitRule struct {
+	// Key is "client_ip", "client_id" or "namespace"
+	Key string `mapstructure:"key"`
+
+	// Rate is how many pings per second are accepted per key value
+	Rate float64 `mapstructure:"rate"`
+
+	// Burst is how many pings are accepted at once. If 0, one second of
+	// rate, and at least one ping.
+	Burst int `mapstructure:"burst"`
+}
+
+// LimitsConfig defines the payload limits of pings. The request body size
+// is limited by max_request_body_size.
+type LimitsConfig struct {
+	// MaxDecompressedBytes is the largest body accepted once a gzip request
+	// is decompressed. If 0, max_request_body_size applies.
+	MaxDecompressedBytes int64 `mapstructure:"max_decompressed_bytes"`
+
+	// MaxEvents is how many events of a ping are kept. If 0, the number is
+	// not limited.
+	MaxEvents int `mapstructure:"max_events"`
+
+	// MaxMetrics is how many metrics of a ping are kept, in type and name
+	// order. If 0, the number is not limited.
+	MaxMetrics int `mapstructure:"max_metrics"`
+
+	// MaxLabels is how many labels of a labeled metric are kept, in name
+	// order. If 0, the number is not limited.
+	MaxLabels int `mapstructure:"max_labels"`
+
+	// MaxStringLength is how many characters of metric names and strings,
+	// labels and event categories, names and extras are kept. If 0, the
+	// length is not limited.
+	MaxStringLength int `mapstructure:"max_string_length"`
+
+	// Action is "truncate" to drop what exceeds the event, metric, label and
+	// string limits and process the rest, or "reject" to refuse the ping
+	// with 413 Payload Too Large
+	// Default: truncate
+	Action string `mapstructure:"action"`
+}
+
+// SessionsConfig defines the configuration for session reconstruction
+type SessionsConfig struct {
+	// Enabled turns on session stitching. Requires a traces pipeline.
+	Enabled bool `mapstructure:"enabled"`
+
+	// IdleTimeout is how long a session is kept after its last ping before
+	// it is closed and its root span is emitted. Must be at least 1s.
+	// Default: 30m
+	IdleTimeout time.Duration `mapstructure:"idle_timeout"`
+
+	// MaxSessions bounds the open sessions kept in memory. Opening a session
+	// over the limit closes the least recently seen one.
+	// Default: 100000
+	MaxSessions int `mapstructure:"max_sessions"`
+}
+
+// PrivacyConfig defines how client_id and session_id are exported
+type PrivacyConfig struct {
+	// IdentifierMode is one of "none" (export as-is), "hash" (replace with an
+	// HMAC-SHA256), "truncate" (keep a prefix) or "drop" (remove entirely)
+	// Default: none
+	IdentifierMode string `mapstructure:"identifier_mode"`
+
+	// HashKey is the HMAC secret used when identifier_mode is "hash"
+	HashKey configopaque.String `mapstructure:"hash_key"`
+
+	// HashKeyEnv names an environment variable holding the HMAC secret,
+	// used when hash_key is empty
+	HashKeyEnv string `mapstructure:"hash_key_env"`
+
+	// TruncateLength is the number of characters kept when identifier_mode
+	// is "truncate"
+	// Default: 8
+	TruncateLength int `mapstructure:"truncate_length"`
+}
+
+// RedactionConfig defines the PII redaction rules applied during conversion.
+// Metrics and events are identified by their Glean name (category.name).
+type RedactionConfig struct {
+	// Patterns are regular expressions whose matches are replaced
+	Patterns []string `mapstructure:"patterns"`
+
+	// AllowMetrics are exempt from all redaction rules
+	AllowMetrics []string `mapstructure:"allow_metrics"`
+
+	// DenyMetrics always have their whole value replaced
+	DenyMetrics []string `mapstructure:"deny_metrics"`
+
+	// StripURLQuery removes query strings and fragments from url metrics
+	StripURLQuery bool `mapstructure:"strip_url_query"`
+
+	// MaxLength truncates values longer than this many characters.
+	// 0 disables truncation.
+	MaxLength int `mapstructure:"max_length"`
+
+	// Replacement is the text substituted for redacted values
+	// Default: [REDACTED]
+	Replacement string `mapstructure:"replacement"`
+}
+
+// RegistryConfig defines where Glean probe definitions are loaded from and
+// how pings that do not match them are handled
+type RegistryConfig struct {
+	// MetricsFiles are paths to Glean metrics.yaml files
+	MetricsFiles []string `mapstructure:"metrics_files"`
+
+	// PingsFiles are paths to Glean pings.yaml files
+	PingsFiles []string `mapstructure:"pings_files"`
+
+	// UndeclaredMetrics is the policy for metrics missing from metrics_files:
+	// "accept" converts them from their JSON value, "flag" additionally marks
+	// them with a glean.undeclared attribute, "drop" skips them and "reject"
+	// rejects the whole ping
+	// Default: accept
+	UndeclaredMetrics string `mapstructure:"undeclared_metrics"`
+
+	// UndeclaredPings is the policy for ping types missing from pings_files:
+	// "accept", "flag" or "reject". Glean built-in pings are always declared.
+	// Default: accept
+	UndeclaredPings string `mapstructure:"undeclared_pings"`
+}
+
+// SchemaValidationConfig defines how pings are validated against the Glean
+// ping schema
+type SchemaValidationConfig struct {
+	// Enabled turns on schema validation
+	Enabled bool `mapstructure:"enabled"`
+
+	// SchemaPath is a local copy of glean.1.schema.json from
+	// mozilla-pipeline-schemas. If empty, pings are only checked against the
+	// built-in ping structure schema, not the upstream Glean schema.
+	SchemaPath string `mapstructure:"schema_path"`
+
+	// OnFailure is "reject" to answer invalid pings with 400 Bad Request, or
+	// "log" to accept them and send an error log record to the logs pipeline
+	// instead of converting them
+	// Default: reject
+	OnFailure string `mapstructure:"on_failure"`
+}
+
+// AllowlistConfig defines the pings accepted by the receiver. An empty list
+// accepts any value.
+type AllowlistConfig struct {
+	// Namespaces are glob patterns matched against {namespace}
+	Namespaces []string `mapstructure:"namespaces"`
+
+	// DocumentTypes are glob patterns matched against {document_type}
+	DocumentTypes []string `mapstructure:"document_types"`
+
+	// DocumentVersions are accepted {document_version} values or inclusive
+	// ranges such as "1-3"
+	DocumentVersions []string `mapstructure:"document_versions"`
+}
+
+// AttributesConfig defines the mapping of client_info and ping_info fields
+// to attributes
+type AttributesConfig struct {
+	// Preset is the built-in mapping: "legacy" or "semconv"
+	// Default: legacy
+	Preset string `mapstructure:"preset"`
+
+	// Mappings override the preset per Glean field, keyed by field name
+	// such as "locale", "attribution.source" or "ping_type"
+	Mappings map[string]AttributeMapping `mapstructure:"mappings"`
+}
+
+// AttributeMapping overrides how a single Glean field is exported
+type AttributeMapping struct {
+	// Name is the attribute name. If empty, the preset name is kept.
+	Name string `mapstructure:"name"`
+
+	// Level is "resource", "scope" or "datapoint". If empty, the preset
+	// level is kept.
+	Level string `mapstructure:"level"`
+
+	// Drop removes the field from the exported telemetry
+	Drop bool `mapstructure:"drop"`
+}
+
+// SourceTagsConfig defines the rules applied to pings carrying X-Source-Tags
+type SourceTagsConfig struct {
+	// Rules maps a source tag, such as "automation", to the action applied
+	// to pings carrying it. Pings without a matching tag are processed
+	// normally.
+	Rules map[string]SourceTagRule `mapstructure:"rules"`
+}
+
+// SourceTagRule defines the action for pings carrying a source tag
+type SourceTagRule struct {
+	// Action is "drop" (accept the ping but do not process it), "tag"
+	// (export the tag as the glean.source_tags attribute) or "route" (tag
+	// and set the glean.route attribute and client metadata)
+	Action string `mapstructure:"action"`
+
+	// Route is the route name set by the route action
+	Route string `mapstructure:"route"`
+}
+
+// PendingPingsConfig defines the directories read in the Glean SDK
+// pending_pings format
+type PendingPingsConfig struct {
+	// Directories are polled for ping files. If empty, file ingest is
+	// disabled.
+	Directories []string `mapstructure:"directories"`
+
+	// PollInterval is how often the directories are scanned for new files
+	// Default: 10s
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+
+	// DeleteProcessed removes files once they have been ingested or
+	// permanently refused, as the SDK does after uploading. Otherwise files
+	// are remembered and only read again when they change.
+	DeleteProcessed bool `mapstructure:"delete_processed"`
+}
+
+// ReplayConfig defines NDJSON archives of raw pings, one envelope with the
+// submission URI, headers and payload per line, replayed through the receiver
+type ReplayConfig struct {
+	// Paths are archive files or glob patterns. Files may be
+	// gzip-compressed. If empty, replay is disabled.
+	Paths []string `mapstructure:"paths"`
+
+	// Rate limits replay to this many pings per second, up to 1000000. If
+	// 0, pings are replayed as fast as the pipeline accepts them.
+	Rate float64 `mapstructure:"rate"`
+
+	// TimeShift is added to the submission and ping_info times of replayed
+	// pings, for example 720h to move a month-old archive to today
+	TimeShift time.Duration `mapstructure:"time_shift"`
+}
+
+// KafkaConfig defines consuming the ping envelopes edge servers write to
+// Kafka, in the same format as replayed archives
+type KafkaConfig struct {
+	// Brokers are the seed brokers of the cluster. If empty, Kafka ingest is
+	// disabled.
+	Brokers []string `mapstructure:"brokers"`
+
+	// Topics are consumed by the receiver
+	Topics []string `mapstructure:"topics"`
+
+	// GroupID is the consumer group offsets are committed for
+	// Default: glean-receiver
+	GroupID string `mapstructure:"group_id"`
+
+	// InitialOffset is where consumption starts for partitions without a
+	// committed offset: "latest" or "earliest"
+	// Default: latest
+	InitialOffset string `mapstructure:"initial_offset"`
+
+	// RetryBackoff is how long to wait before retrying a ping the pipeline
+	// failed to accept
+	// Default: 1s
+	RetryBackoff time.Duration `mapstructure:"retry_backoff"`
+
+	// MaxRetries is how many times a ping the pipeline fails to accept is
+	// retried before it is skipped and committed, losing it. 0 retries until
+	// the ping is accepted, so a pipeline outage stalls the partition rather
+	// than dropping pings.
+	// Default: 0
+	MaxRetries int `mapstructure:"max_retries"`
+}
+
+// DebugConfig defines the in-memory debug view of pings tagged with an
+// X-Debug-ID header and the live tail of every ping
+type DebugConfig struct {
+	// Enabled serves the debug UI, API and live tail. Captured pings are
+	// kept after the privacy and redaction settings are applied, the raw
+	// body is never kept.
+	Enabled bool `mapstructure:"enabled"`
+
+	// Endpoint is the TCP address the debug view is served on, separate
+	// from the ingestion endpoint. Keep it on a loopback or otherwise
+	// developer-only interface.
+	// Default: localhost:9890
+	Endpoint string `mapstructure:"endpoint"`
+
+	// Path is the prefix the debug UI and API are served under
+	// Default: /debug
+	Path string `mapstructure:"path"`
+
+	// MaxPingsPerID is how many of the latest pings are kept per debug ID
+	// Default: 20
+	MaxPingsPerID int `mapstructure:"max_pings_per_id"`
+
+	// MaxDebugIDs is how many debug IDs are kept. The least recently seen
+	// debug ID is evicted first.
+	// Default: 100
+	MaxDebugIDs int `mapstructure:"max_debug_ids"`
+}
+
+// DeletionRequestConfig defines how deletion-request pings are handled
+type DeletionRequestConfig struct {
+	// ForwardURL is an HTTP endpoint that additionally receives raw
+	// deletion-request pings. If empty, they are only sent to forward_url.
+	ForwardURL string `mapstructure:"forward_url"`
+}
+
+// maxRequestBodySize returns the largest ping body accepted over HTTP
+func (cfg *Config) maxRequestBodySize() int64 {
+	if cfg.MaxRequestBodySize > 0 {
+		return cfg.MaxRequestBodySize
+	}
+	return defaultMaxRequestBodySize
 }
 
 func (cfg *Config) GetPath() string {
@@ -65,6 +530,59 @@ func (cfg *Config) Validate() error {
 		return errors.New("path cannot be empty")
 	}
 
+	if err := validateRoutes(cfg.routes()); err != nil {
+		return err
+	}
+
+	if cfg.MaxRequestBodySize < 0 {
+		return errors.New("max_request_body_size must not be negative")
+	}
+	if cfg.RetryAfter < 0 {
+		return errors.New("retry_after must not be negative")
+	}
+	if cfg.Admission.MaxInFlightPings < 0 {
+		return errors.New("admission.max_in_flight_pings must not be negative")
+	}
+	if cfg.Admission.MaxInFlightBytes < 0 {
+		return errors.New("admission.max_in_flight_bytes must not be negative")
+	}
+
+	if len(cfg.RateLimit.Rules) > 0 && cfg.RateLimit.MaxKeys <= 0 {
+		return errors.New("rate_limit.max_keys must be positive")
+	}
+	if cfg.RateLimit.ClientIPHeader != "" && cfg.RateLimit.TrustedProxies <= 0 {
+		return errors.New("rate_limit.trusted_proxies must be positive when client_ip_header is set")
+	}
+	for _, rule := range cfg.RateLimit.Rules {
+		switch rule.Key {
+		case rateLimitKeyClientIP, rateLimitKeyClientID, rateLimitKeyNamespace:
+		default:
+			return fmt.Errorf("invalid rate_limit key %q", rule.Key)
+		}
+		if rule.Rate <= 0 {
+			return fmt.Errorf("rate_limit rate for %s must be positive", rule.Key)
+		}
+		if rule.Burst < 0 {
+			return fmt.Errorf("rate_limit burst for %s must not be negative", rule.Key)
+		}
+	}
+
+	if cfg.Limits.MaxDecompressedBytes < 0 || cfg.Limits.MaxEvents < 0 || cfg.Limits.MaxMetrics < 0 ||
+		cfg.Limits.MaxLabels < 0 || cfg.Limits.MaxStringLength < 0 {
+		return errors.New("limits must not be negative")
+	}
+	switch cfg.Limits.Action {
+	case "", limitActionTruncate, limitActionReject:
+	default:
+		return fmt.Errorf("invalid limits.action %q", cfg.Limits.Action)
+	}
+
+	for _, addr := range cfg.AdditionalEndpoints {
+		if err := validateListenAddr(addr); err != nil {
+			return fmt.Errorf("invalid additional_endpoints entry: %w", err)
+		}
+	}
+
 	// Validate forward URL if provided
 	if cfg.ForwardURL != "" {
 		if !strings.HasPrefix(cfg.ForwardURL, "http://") && !strings.HasPrefix(cfg.ForwardURL, "https://") {
@@ -72,5 +590,122 @@ func (cfg *Config) Validate() error {
 		}
 	}
 
+	if cfg.DeletionRequest.ForwardURL != "" {
+		if !strings.HasPrefix(cfg.DeletionRequest.ForwardURL, "http://") && !strings.HasPrefix(cfg.DeletionRequest.ForwardURL, "https://") {
+			return errors.New("deletion_request.forward_url must start with http:// or https://")
+		}
+	}
+
+	for _, pattern := range cfg.Redaction.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+	}
+	if cfg.Redaction.MaxLength < 0 {
+		return errors.New("redaction.max_length cannot be negative")
+	}
+
+	switch cfg.Registry.UndeclaredMetrics {
+	case "", undeclaredAccept, undeclaredFlag, undeclaredDrop, undeclaredReject:
+	default:
+		return fmt.Errorf("invalid registry.undeclared_metrics %q", cfg.Registry.UndeclaredMetrics)
+	}
+	switch cfg.Registry.UndeclaredPings {
+	case "", undeclaredAccept, undeclaredFlag, undeclaredReject:
+	default:
+		return fmt.Errorf("invalid registry.undeclared_pings %q", cfg.Registry.UndeclaredPings)
+	}
+
+	switch cfg.SchemaValidation.OnFailure {
+	case "", validationFailureReject, validationFailureLog:
+	default:
+		return fmt.Errorf("invalid schema_validation.on_failure %q", cfg.SchemaValidation.OnFailure)
+	}
+
+	if _, err := newPingAllowlist(cfg.Allowlist); err != nil {
+		return err
+	}
+
+	if _, err := newAttributeMapper(cfg); err != nil {
+		return err
+	}
+
+	if _, err := newSourceTagRouter(cfg.SourceTags); err != nil {
+		return err
+	}
+
+	if len(cfg.PendingPings.Directories) > 0 && cfg.PendingPings.PollInterval <= 0 {
+		return errors.New("pending_pings.poll_interval must be positive")
+	}
+
+	if cfg.Replay.Rate < 0 {
+		return errors.New("replay.rate must not be negative")
+	}
+	// Written as a negation so that NaN is refused too
+	if !(cfg.Replay.Rate <= maxReplayRate) {
+		return fmt.Errorf("replay.rate must be at most %g pings per second", float64(maxReplayRate))
+	}
+	for _, pattern := range cfg.Replay.Paths {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid replay path %q: %w", pattern, err)
+		}
+	}
+
+	if len(cfg.Kafka.Brokers) > 0 {
+		if len(cfg.Kafka.Topics) == 0 {
+			return errors.New("kafka.topics must not be empty")
+		}
+		if cfg.Kafka.GroupID == "" {
+			return errors.New("kafka.group_id must not be empty")
+		}
+		switch cfg.Kafka.InitialOffset {
+		case kafkaOffsetLatest, kafkaOffsetEarliest:
+		default:
+			return fmt.Errorf("invalid kafka.initial_offset %q", cfg.Kafka.InitialOffset)
+		}
+		if cfg.Kafka.RetryBackoff <= 0 {
+			return errors.New("kafka.retry_backoff must be positive")
+		}
+		if cfg.Kafka.MaxRetries < 0 {
+			return errors.New("kafka.max_retries must not be negative")
+		}
+	}
+
+	if cfg.Debug.Enabled {
+		if cfg.Debug.Endpoint == "" {
+			return errors.New("debug.endpoint must be specified")
+		}
+		if !strings.HasPrefix(cfg.Debug.Path, "/") {
+			return errors.New("debug.path must start with /")
+		}
+		if cfg.Debug.MaxPingsPerID <= 0 {
+			return errors.New("debug.max_pings_per_id must be positive")
+		}
+		if cfg.Debug.MaxDebugIDs <= 0 {
+			return errors.New("debug.max_debug_ids must be positive")
+		}
+	}
+
+	if cfg.Sessions.Enabled && cfg.Sessions.IdleTimeout < time.Second {
+		return errors.New("sessions.idle_timeout must be at least 1s")
+	}
+	if cfg.Sessions.Enabled && cfg.Sessions.MaxSessions <= 0 {
+		return errors.New("sessions.max_sessions must be positive")
+	}
+
+	switch cfg.Privacy.IdentifierMode {
+	case "", identifierModeNone, identifierModeDrop:
+	case identifierModeHash:
+		if cfg.Privacy.HashKey == "" && cfg.Privacy.HashKeyEnv == "" {
+			return errors.New("privacy.hash_key or privacy.hash_key_env is required when identifier_mode is hash")
+		}
+	case identifierModeTruncate:
+		if cfg.Privacy.TruncateLength <= 0 {
+			return errors.New("privacy.truncate_length must be positive when identifier_mode is truncate")
+		}
+	default:
+		return fmt.Errorf("invalid privacy.identifier_mode %q", cfg.Privacy.IdentifierMode)
+	}
+
 	return nil
 }
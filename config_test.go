@@ -6,6 +6,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"go.opentelemetry.io/collector/config/confighttp"
+	"go.opentelemetry.io/collector/config/confignet"
 )
 
 func TestConfigValidate(t *testing.T) {
@@ -81,6 +82,407 @@ func TestConfigValidate(t *testing.T) {
 			}(),
 			wantErr: true,
 		},
+		{
+			name: "invalid deletion request forward URL",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig:    cfg,
+					Path:            "/submit/telemetry",
+					DeletionRequest: DeletionRequestConfig{ForwardURL: "deletion.example.com"},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "invalid redaction pattern",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					Redaction:    RedactionConfig{Patterns: []string{"[unclosed"}},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "invalid undeclared pings policy",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					Registry:     RegistryConfig{UndeclaredPings: "drop"},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "invalid allowlist version range",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					Allowlist:    AllowlistConfig{DocumentVersions: []string{"1-x"}},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "sessions enabled without max sessions",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					Sessions:     SessionsConfig{Enabled: true, IdleTimeout: time.Minute},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "sessions idle timeout under a second",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					Sessions:     SessionsConfig{Enabled: true, IdleTimeout: time.Nanosecond, MaxSessions: 10},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "sessions enabled without idle timeout",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					Sessions:     SessionsConfig{Enabled: true},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "hash identifiers without key",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					Privacy:      PrivacyConfig{IdentifierMode: "hash"},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "hash identifiers with key from env",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					Privacy:      PrivacyConfig{IdentifierMode: "hash", HashKeyEnv: "GLEAN_HASH_KEY"},
+				}
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "semconv attribute preset",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					Attributes:   AttributesConfig{Preset: "semconv"},
+				}
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "invalid attribute mapping level",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					Attributes: AttributesConfig{
+						Mappings: map[string]AttributeMapping{"locale": {Level: "span"}},
+					},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "debug view without limits",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					Debug:        DebugConfig{Enabled: true, Endpoint: "localhost:9890", Path: "/debug"},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "debug view without endpoint",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					Debug:        DebugConfig{Enabled: true, Path: "/debug", MaxPingsPerID: 20, MaxDebugIDs: 100},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "debug view with relative path",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					Debug:        DebugConfig{Enabled: true, Endpoint: "localhost:9890", Path: "debug", MaxPingsPerID: 20, MaxDebugIDs: 100},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "invalid source tag action",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					SourceTags: SourceTagsConfig{
+						Rules: map[string]SourceTagRule{"automation": {Action: "discard"}},
+					},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "pending pings without poll interval",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					PendingPings: PendingPingsConfig{Directories: []string{"/tmp/pending_pings"}},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "negative replay rate",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					Replay:       ReplayConfig{Paths: []string{"/tmp/pings.ndjson"}, Rate: -1},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "replay rate too high",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					Replay:       ReplayConfig{Paths: []string{"/tmp/pings.ndjson"}, Rate: 2e9},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "invalid replay path pattern",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					Replay:       ReplayConfig{Paths: []string{"/tmp/[pings.ndjson"}},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "kafka without topics",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					Kafka:        KafkaConfig{Brokers: []string{"localhost:9092"}, GroupID: "glean", InitialOffset: "latest", RetryBackoff: time.Second},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "negative kafka max retries",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					Kafka:        KafkaConfig{Brokers: []string{"localhost:9092"}, Topics: []string{"pings"}, GroupID: "glean", InitialOffset: "latest", RetryBackoff: time.Second, MaxRetries: -1},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "invalid kafka initial offset",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					Kafka:        KafkaConfig{Brokers: []string{"localhost:9092"}, Topics: []string{"pings"}, GroupID: "glean", InitialOffset: "oldest", RetryBackoff: time.Second},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "additional endpoint without address",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig:        cfg,
+					Path:                "/submit/telemetry",
+					AdditionalEndpoints: []confignet.AddrConfig{{Transport: confignet.TransportTypeUnix}},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "negative retry_after",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					RetryAfter:   -time.Second,
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "negative admission limit",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					Admission:    AdmissionConfig{MaxInFlightBytes: -1},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "invalid rate limit key",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					RateLimit: RateLimitConfig{
+						Rules:   []RateLimitRule{{Key: "user_agent", Rate: 1}},
+						MaxKeys: 100,
+					},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "client ip header without trusted proxies",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					RateLimit: RateLimitConfig{
+						Rules:          []RateLimitRule{{Key: rateLimitKeyClientIP, Rate: 1}},
+						ClientIPHeader: "X-Forwarded-For",
+						MaxKeys:        100,
+					},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "invalid limits action",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					Limits:       LimitsConfig{MaxEvents: 100, Action: "drop"},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "route without namespace",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					Routes:       []RouteConfig{{Path: "/glean/{document_type}", DocumentVersion: "1"}},
+				}
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "invalid identifier mode",
+			config: func() *Config {
+				cfg := confighttp.NewDefaultServerConfig()
+				cfg.NetAddr.Endpoint = "localhost:9888"
+				return &Config{
+					ServerConfig: cfg,
+					Path:         "/submit/telemetry",
+					Privacy:      PrivacyConfig{IdentifierMode: "encrypt"},
+				}
+			}(),
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -101,6 +503,18 @@ func TestCreateDefaultConfig(t *testing.T) {
 	assert.Equal(t, "localhost:9888", cfg.ServerConfig.NetAddr.Endpoint)
 	assert.Equal(t, "/submit/{namespace}/{document_type}/{document_version}/{document_id}", cfg.Path)
 	assert.Equal(t, 20*time.Second, cfg.ServerConfig.ReadHeaderTimeout)
+	assert.False(t, cfg.Sessions.Enabled)
+	assert.Equal(t, 30*time.Minute, cfg.Sessions.IdleTimeout)
+	assert.Equal(t, 100000, cfg.Sessions.MaxSessions)
+	assert.Equal(t, []string{"X-Debug-ID", "X-Source-Tags", "X-Telemetry-Agent", "User-Agent"}, cfg.RequestHeaders)
+	assert.Equal(t, 10*time.Second, cfg.PendingPings.PollInterval)
+	assert.Equal(t, "glean-receiver", cfg.Kafka.GroupID)
+	assert.Equal(t, kafkaOffsetLatest, cfg.Kafka.InitialOffset)
+	assert.Zero(t, cfg.Kafka.MaxRetries)
+	assert.Equal(t, 1, cfg.RateLimit.TrustedProxies)
+	assert.Equal(t, "localhost:9890", cfg.Debug.Endpoint)
+	assert.False(t, cfg.GRPC.HasValue())
+	assert.Equal(t, "localhost:9889", cfg.GRPC.GetOrInsertDefault().NetAddr.Endpoint)
 }
 
 func TestGetPath(t *testing.T) {
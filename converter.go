@@ -1,6 +1,8 @@
 package gleanreceiver
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
 	"fmt"
 	"slices"
 	"strconv"
@@ -9,45 +11,144 @@ import (
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
 )
 
+// converterSettings holds the configuration-derived state used during
+// conversion. The zero value converts pings using type heuristics.
+type converterSettings struct {
+	// attributes maps client_info and ping_info fields to attributes
+	attributes *attributeMapper
+
+	// registry holds the metrics.yaml and pings.yaml definitions, if loaded
+	registry *gleanRegistry
+
+	// undeclaredMetrics is the policy for metrics missing from the registry
+	undeclaredMetrics string
+
+	// undeclaredPings is the policy for ping types missing from the registry
+	undeclaredPings string
+}
+
+// newConverterSettings loads the registry and conversion policies from cfg
+func newConverterSettings(cfg *Config) (converterSettings, error) {
+	var settings converterSettings
+	attributes, err := newAttributeMapper(cfg)
+	if err != nil {
+		return settings, err
+	}
+	settings.attributes = attributes
+
+	if len(cfg.Registry.MetricsFiles) == 0 && len(cfg.Registry.PingsFiles) == 0 {
+		return settings, nil
+	}
+
+	registry, err := loadRegistry(cfg.Registry.MetricsFiles, cfg.Registry.PingsFiles)
+	if err != nil {
+		return settings, err
+	}
+	settings.registry = registry
+
+	// Only apply policies for the kinds of definitions that were loaded
+	settings.undeclaredMetrics = undeclaredAccept
+	if len(cfg.Registry.MetricsFiles) > 0 && cfg.Registry.UndeclaredMetrics != "" {
+		settings.undeclaredMetrics = cfg.Registry.UndeclaredMetrics
+	}
+	settings.undeclaredPings = undeclaredAccept
+	if len(cfg.Registry.PingsFiles) > 0 && cfg.Registry.UndeclaredPings != "" {
+		settings.undeclaredPings = cfg.Registry.UndeclaredPings
+	}
+
+	return settings, nil
+}
+
+// flagUndeclaredPing marks attrs when the ping type is not declared in the
+// registry and undeclared pings are flagged
+func (s converterSettings) flagUndeclaredPing(attrs pcommon.Map, ping *GleanPing) {
+	if s.registry == nil || s.undeclaredPings != undeclaredFlag {
+		return
+	}
+	if !s.registry.hasPing(ping.Request.DocumentType) {
+		attrs.PutBool("glean.undeclared", true)
+	}
+}
+
 // convertToMetrics converts a Glean ping to OpenTelemetry metrics
-func convertToMetrics(ping *GleanPing) (pmetric.Metrics, error) {
+func convertToMetrics(ping *GleanPing, settings converterSettings) (pmetric.Metrics, error) {
 	metrics := pmetric.NewMetrics()
 	rm := metrics.ResourceMetrics().AppendEmpty()
 
-	// Add resource attributes from client_info
-	addClientInfoAttributes(rm.Resource().Attributes(), &ping.ClientInfo)
+	// Add client_info and ping_info attributes at their mapped levels
+	attrs := settings.attributes.mapPing(ping)
+	attrs.resource.CopyTo(rm.Resource().Attributes())
 
 	scopeMetrics := rm.ScopeMetrics().AppendEmpty()
 	scope := scopeMetrics.Scope()
 	scope.SetName("glean")
-	// Add ping_info attributes to scope
-	addPingInfoAttributes(scope.Attributes(), &ping.PingInfo)
+	attrs.scope.CopyTo(scope.Attributes())
+	mergeAttributes(scope.Attributes(), attrs.pingInfo)
+	settings.flagUndeclaredPing(scope.Attributes(), ping)
 
 	// Process all metric categories
 	if ping.Metrics != nil {
-		if err := processMetrics(scopeMetrics, ping.Metrics); err != nil {
+		if err := processMetrics(scopeMetrics, ping.Metrics, settings); err != nil {
 			return metrics, err
 		}
 	}
 
+	// Report experiment enrollments as a dedicated metric
+	addExperimentEnrollmentMetric(scopeMetrics, ping.PingInfo.Experiments)
+
+	if attrs.dataPoint.Len() > 0 {
+		forEachDataPointAttributes(scopeMetrics.Metrics(), 0, func(dpAttrs pcommon.Map) {
+			mergeAttributes(dpAttrs, attrs.dataPoint)
+		})
+	}
+
+	// Replayed pings are placed at the time they were measured rather than now
+	if ping.Request.Replayed && !ping.PingInfo.EndTime.IsZero() {
+		setDataPointTimestamps(scopeMetrics.Metrics(), pcommon.NewTimestampFromTime(ping.PingInfo.EndTime))
+	}
+
 	return metrics, nil
 }
 
+// setDataPointTimestamps sets the timestamp of every data point in metrics
+func setDataPointTimestamps(metrics pmetric.MetricSlice, timestamp pcommon.Timestamp) {
+	for i := 0; i < metrics.Len(); i++ {
+		metric := metrics.At(i)
+		switch metric.Type() {
+		case pmetric.MetricTypeGauge:
+			for j := 0; j < metric.Gauge().DataPoints().Len(); j++ {
+				metric.Gauge().DataPoints().At(j).SetTimestamp(timestamp)
+			}
+		case pmetric.MetricTypeSum:
+			for j := 0; j < metric.Sum().DataPoints().Len(); j++ {
+				metric.Sum().DataPoints().At(j).SetTimestamp(timestamp)
+			}
+		case pmetric.MetricTypeHistogram:
+			for j := 0; j < metric.Histogram().DataPoints().Len(); j++ {
+				metric.Histogram().DataPoints().At(j).SetTimestamp(timestamp)
+			}
+		}
+	}
+}
+
 // convertToEventLogs converts Glean events to OpenTelemetry event logs
-func convertToEventLogs(ping *GleanPing) (plog.Logs, error) {
+func convertToEventLogs(ping *GleanPing, settings converterSettings) (plog.Logs, error) {
 	logs := plog.NewLogs()
 	rl := logs.ResourceLogs().AppendEmpty()
 
-	// Add resource attributes from client_info
-	addClientInfoAttributes(rl.Resource().Attributes(), &ping.ClientInfo)
-
-	// Add ping_info attributes
-	addPingInfoAttributes(rl.Resource().Attributes(), &ping.PingInfo)
+	// Add client_info and ping_info attributes at their mapped levels
+	attrs := settings.attributes.mapPing(ping)
+	attrs.resource.CopyTo(rl.Resource().Attributes())
+	// Event logs carry ping_info on the resource
+	mergeAttributes(rl.Resource().Attributes(), attrs.pingInfo)
+	settings.flagUndeclaredPing(rl.Resource().Attributes(), ping)
 
 	scopeLogs := rl.ScopeLogs().AppendEmpty()
 	scopeLogs.Scope().SetName("glean")
+	attrs.scope.CopyTo(scopeLogs.Scope().Attributes())
 
 	// Convert each event to an event log record
 	for _, event := range ping.Events {
@@ -68,65 +169,260 @@ func convertToEventLogs(ping *GleanPing) (plog.Logs, error) {
 		for k, v := range event.Extra {
 			logRecord.Attributes().PutStr(k, v)
 		}
+		mergeAttributes(logRecord.Attributes(), attrs.dataPoint)
+		mergeAttributes(logRecord.Attributes(), attrs.record)
 	}
 
 	return logs, nil
 }
 
-// addClientInfoAttributes adds client_info fields as resource attributes
-func addClientInfoAttributes(attrs pcommon.Map, clientInfo *ClientInfo) {
-	if clientInfo.ClientID != "" {
-		attrs.PutStr("client.id", clientInfo.ClientID)
+// convertToDeletionLog converts a deletion-request ping to a structured log
+// record recording which client asked for its data to be deleted
+func convertToDeletionLog(ping *GleanPing, settings converterSettings) (plog.Logs, error) {
+	logs := plog.NewLogs()
+	rl := logs.ResourceLogs().AppendEmpty()
+
+	// Add client_info resource attributes
+	attrs := newMappedAttributes()
+	settings.attributes.mapService(attrs, ping.Request.Namespace, &ping.ClientInfo)
+	settings.attributes.mapRequest(attrs, &ping.Request)
+	settings.attributes.mapClientInfo(attrs, &ping.ClientInfo)
+	attrs.resource.CopyTo(rl.Resource().Attributes())
+
+	scopeLogs := rl.ScopeLogs().AppendEmpty()
+	scopeLogs.Scope().SetName("glean")
+
+	logRecord := scopeLogs.LogRecords().AppendEmpty()
+	logRecord.SetTimestamp(pcommon.NewTimestampFromTime(time.Now()))
+	logRecord.SetSeverityNumber(plog.SeverityNumberInfo)
+	logRecord.Body().SetStr(deletionRequestDocumentType)
+
+	recordAttrs := logRecord.Attributes()
+	recordAttrs.PutStr("event.name", "glean.deletion_request")
+	// The client_id is empty when dropped by the privacy settings
+	if ping.ClientInfo.ClientID != "" {
+		recordAttrs.PutStr("client.id", ping.ClientInfo.ClientID)
 	}
-	if clientInfo.SessionID != "" {
-		attrs.PutStr("session.id", clientInfo.SessionID)
+	recordAttrs.PutStr("namespace", ping.Request.Namespace)
+	mergeAttributes(recordAttrs, attrs.record)
+	if ping.PingInfo.Reason != "" {
+		recordAttrs.PutStr("ping.reason", ping.PingInfo.Reason)
 	}
-	if clientInfo.SessionCount > 0 {
-		attrs.PutInt("session.count", int64(clientInfo.SessionCount))
+
+	return logs, nil
+}
+
+// convertToValidationErrorLog converts a ping that failed schema validation
+// to an error log record listing its violations
+func convertToValidationErrorLog(gleanRequest GleanPingRequest, violations []schemaViolation, settings converterSettings) (plog.Logs, error) {
+	logs := plog.NewLogs()
+	rl := logs.ResourceLogs().AppendEmpty()
+
+	// The ping was not decoded, so only the service is known
+	resourceAttrs := newMappedAttributes()
+	settings.attributes.mapService(resourceAttrs, gleanRequest.Namespace, &ClientInfo{})
+	resourceAttrs.resource.CopyTo(rl.Resource().Attributes())
+
+	scopeLogs := rl.ScopeLogs().AppendEmpty()
+	scopeLogs.Scope().SetName("glean")
+
+	logRecord := scopeLogs.LogRecords().AppendEmpty()
+	logRecord.SetTimestamp(pcommon.NewTimestampFromTime(time.Now()))
+	logRecord.SetSeverityNumber(plog.SeverityNumberError)
+	logRecord.SetSeverityText("ERROR")
+	logRecord.Body().SetStr("Glean ping failed schema validation")
+
+	attrs := logRecord.Attributes()
+	attrs.PutStr("event.name", "glean.validation_failure")
+	attrs.PutStr("namespace", gleanRequest.Namespace)
+	attrs.PutStr("document.type", gleanRequest.DocumentType)
+	attrs.PutStr("document.version", gleanRequest.DocumentVersion)
+	attrs.PutStr("document.id", gleanRequest.DocumentID)
+
+	errs := attrs.PutEmptySlice("glean.validation.errors")
+	for _, violation := range violations {
+		errs.AppendEmpty().SetStr(fmt.Sprintf("%s: %s", violation.Path, violation.Message))
 	}
-	if clientInfo.AppBuild != "" {
-		attrs.PutStr("service.version", clientInfo.AppBuild)
+
+	return logs, nil
+}
+
+// convertToTraces converts a Glean ping lifecycle to OpenTelemetry spans.
+// A root span covers ping_info start_time..end_time and every timespan and
+// timing_distribution metric becomes a child span starting at the ping start.
+// Missing ping_info times fall back to the submission time; a ping with
+// neither produces no spans.
+func convertToTraces(ping *GleanPing, settings converterSettings) (ptrace.Traces, error) {
+	traces := ptrace.NewTraces()
+
+	startTime, endTime := ping.PingInfo.StartTime, ping.PingInfo.EndTime
+	if startTime.IsZero() {
+		startTime = ping.Request.SubmissionTime
 	}
-	if clientInfo.AppDisplayVersion != "" {
-		attrs.PutStr("app.version", clientInfo.AppDisplayVersion)
+	if endTime.IsZero() {
+		endTime = startTime
 	}
-	if clientInfo.AppChannel != "" {
-		attrs.PutStr("app.channel", clientInfo.AppChannel)
+	if startTime.IsZero() {
+		return traces, nil
 	}
-	if clientInfo.TelemetrySDKBuild != "" {
-		attrs.PutStr("telemetry.sdk.version", clientInfo.TelemetrySDKBuild)
+
+	rs := traces.ResourceSpans().AppendEmpty()
+
+	// Add client_info and ping_info attributes at their mapped levels
+	attrs := settings.attributes.mapPing(ping)
+	attrs.resource.CopyTo(rs.Resource().Attributes())
+
+	scopeSpans := rs.ScopeSpans().AppendEmpty()
+	scope := scopeSpans.Scope()
+	scope.SetName("glean")
+	attrs.scope.CopyTo(scope.Attributes())
+	mergeAttributes(scope.Attributes(), attrs.pingInfo)
+	settings.flagUndeclaredPing(scope.Attributes(), ping)
+
+	// Derive the trace ID from the document ID so that resubmitted pings
+	// map onto the same trace
+	traceID := newTraceID(ping.Request.DocumentID)
+	rootSpanID := newSpanID(ping.Request.DocumentID, "ping")
+
+	pingType := ping.PingInfo.PingType
+	if pingType == "" {
+		pingType = ping.Request.DocumentType
 	}
-	if clientInfo.Architecture != "" {
-		attrs.PutStr("host.arch", clientInfo.Architecture)
+
+	root := scopeSpans.Spans().AppendEmpty()
+	root.SetTraceID(traceID)
+	root.SetSpanID(rootSpanID)
+	root.SetName("ping." + pingType)
+	root.SetKind(ptrace.SpanKindInternal)
+	root.SetStartTimestamp(pcommon.NewTimestampFromTime(startTime))
+	root.SetEndTimestamp(pcommon.NewTimestampFromTime(endTime))
+	mergeAttributes(root.Attributes(), attrs.dataPoint)
+	mergeAttributes(root.Attributes(), attrs.record)
+
+	addDurationSpans := func(metricType string, durationOf func(any) (time.Duration, bool)) {
+		metricsOfType, ok := ping.Metrics[metricType].(map[string]any)
+		if !ok {
+			return
+		}
+
+		// Sort metric names so child spans are emitted in a stable order
+		names := make([]string, 0, len(metricsOfType))
+		for name := range metricsOfType {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+
+		for _, name := range names {
+			duration, ok := durationOf(metricsOfType[name])
+			if !ok {
+				continue
+			}
+			fullName := fmt.Sprintf("%s.%s", metricType, name)
+
+			span := scopeSpans.Spans().AppendEmpty()
+			span.SetTraceID(traceID)
+			span.SetSpanID(newSpanID(ping.Request.DocumentID, fullName))
+			span.SetParentSpanID(rootSpanID)
+			span.SetName(fullName)
+			span.SetKind(ptrace.SpanKindInternal)
+			span.SetStartTimestamp(pcommon.NewTimestampFromTime(startTime))
+			span.SetEndTimestamp(pcommon.NewTimestampFromTime(startTime.Add(duration)))
+			span.Attributes().PutStr("glean.metric.type", metricType)
+			mergeAttributes(span.Attributes(), attrs.dataPoint)
+			mergeAttributes(span.Attributes(), attrs.record)
+		}
 	}
-	if clientInfo.DeviceManufacturer != "" {
-		attrs.PutStr("device.manufacturer", clientInfo.DeviceManufacturer)
+
+	addDurationSpans("timespan", timespanDuration)
+	addDurationSpans("timing_distribution", timingDistributionDuration)
+
+	return traces, nil
+}
+
+// timespanDuration extracts the duration of a Glean timespan metric
+// ({"time_unit": "millisecond", "value": 123})
+func timespanDuration(value any) (time.Duration, bool) {
+	data, ok := value.(map[string]any)
+	if !ok {
+		return 0, false
 	}
-	if clientInfo.DeviceModel != "" {
-		attrs.PutStr("device.model.name", clientInfo.DeviceModel)
+	raw, ok := data["value"]
+	if !ok {
+		return 0, false
 	}
-	if clientInfo.OS != "" {
-		attrs.PutStr("os.type", clientInfo.OS)
+
+	unit := time.Millisecond
+	if timeUnit, ok := data["time_unit"].(string); ok {
+		if unit, ok = timeUnitDurations[timeUnit]; !ok {
+			return 0, false
+		}
 	}
-	if clientInfo.OSVersion != "" {
-		attrs.PutStr("os.version", clientInfo.OSVersion)
+
+	return time.Duration(toInt64(raw)) * unit, true
+}
+
+// timingDistributionDuration extracts the accumulated duration of a Glean
+// timing_distribution metric, whose sum is always reported in nanoseconds
+func timingDistributionDuration(value any) (time.Duration, bool) {
+	data, ok := value.(map[string]any)
+	if !ok {
+		return 0, false
 	}
-	if clientInfo.Locale != "" {
-		attrs.PutStr("host.locale", clientInfo.Locale)
+	sum, ok := data["sum"]
+	if !ok {
+		return 0, false
 	}
+
+	return time.Duration(toInt64(sum)), true
 }
 
-// addPingInfoAttributes adds ping_info fields as resource attributes
-func addPingInfoAttributes(attrs pcommon.Map, pingInfo *PingInfo) {
-	attrs.PutInt("ping.seq", int64(pingInfo.Seq))
-	attrs.PutStr("ping.type", pingInfo.PingType)
-	if pingInfo.Reason != "" {
-		attrs.PutStr("ping.reason", pingInfo.Reason)
+// timeUnitDurations maps Glean time_unit values to durations
+var timeUnitDurations = map[string]time.Duration{
+	"nanosecond":  time.Nanosecond,
+	"microsecond": time.Microsecond,
+	"millisecond": time.Millisecond,
+	"second":      time.Second,
+	"minute":      time.Minute,
+	"hour":        time.Hour,
+	"day":         24 * time.Hour,
+}
+
+// addExperimentEnrollmentMetric adds a gauge with one data point per active
+// experiment so results can be split by branch
+func addExperimentEnrollmentMetric(scopeMetrics pmetric.ScopeMetrics, experiments map[string]Experiment) {
+	if len(experiments) == 0 {
+		return
+	}
+
+	metric := scopeMetrics.Metrics().AppendEmpty()
+	metric.SetName("glean.experiment.enrollment")
+	metric.SetDescription("Active experiment enrollments reported in ping_info")
+	metric.SetUnit("1")
+
+	gauge := metric.SetEmptyGauge()
+
+	// Sort experiment IDs for deterministic data point ordering
+	ids := make([]string, 0, len(experiments))
+	for id := range experiments {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	for _, id := range ids {
+		experiment := experiments[id]
+		dp := gauge.DataPoints().AppendEmpty()
+		dp.SetTimestamp(pcommon.NewTimestampFromTime(time.Now()))
+		dp.SetIntValue(1)
+		dp.Attributes().PutStr("experiment.id", id)
+		dp.Attributes().PutStr("experiment.branch", experiment.Branch)
+		if experiment.Extra.Type != "" {
+			dp.Attributes().PutStr("experiment.type", experiment.Extra.Type)
+		}
 	}
 }
 
 // processMetrics processes all metric categories and types
-func processMetrics(scopeMetrics pmetric.ScopeMetrics, metricsMap map[string]any) error {
+func processMetrics(scopeMetrics pmetric.ScopeMetrics, metricsMap map[string]any, settings converterSettings) error {
 	for category, categoryData := range metricsMap {
 		categoryMap, ok := categoryData.(map[string]any)
 		if !ok {
@@ -136,23 +432,30 @@ func processMetrics(scopeMetrics pmetric.ScopeMetrics, metricsMap map[string]any
 		for metricName, metricValue := range categoryMap {
 			fullName := fmt.Sprintf("%s.%s", category, metricName)
 
-			switch v := metricValue.(type) {
-			case bool:
-				addGaugeMetric(scopeMetrics, fullName, boolToFloat(v))
-			case float64:
-				addGaugeMetric(scopeMetrics, fullName, v)
-			case int64:
-				addCounterMetric(scopeMetrics, fullName, v)
-			case string:
-				addStringMetric(scopeMetrics, fullName, v)
-			case map[string]any:
-				// Handle complex metric types (distributions, rates, etc.)
-				if err := processComplexMetric(scopeMetrics, fullName, v); err != nil {
-					return err
+			if settings.registry != nil {
+				// Use the declared type, unit and description when available
+				if definition, declared := settings.registry.lookupMetric(metricName); declared {
+					handled, err := processDeclaredMetric(scopeMetrics, fullName, definition, metricValue)
+					if err != nil {
+						return err
+					}
+					if handled {
+						continue
+					}
+				} else if settings.undeclaredMetrics == undeclaredDrop {
+					continue
+				} else if settings.undeclaredMetrics == undeclaredFlag {
+					first := scopeMetrics.Metrics().Len()
+					if err := processUntypedMetric(scopeMetrics, fullName, metricValue); err != nil {
+						return err
+					}
+					flagUndeclaredMetrics(scopeMetrics.Metrics(), first)
+					continue
 				}
-			case []any:
-				// Handle string lists
-				addStringListMetric(scopeMetrics, fullName, v)
+			}
+
+			if err := processUntypedMetric(scopeMetrics, fullName, metricValue); err != nil {
+				return err
 			}
 		}
 	}
@@ -160,11 +463,136 @@ func processMetrics(scopeMetrics pmetric.ScopeMetrics, metricsMap map[string]any
 	return nil
 }
 
+// processUntypedMetric converts a metric by inferring its type from its JSON value
+func processUntypedMetric(scopeMetrics pmetric.ScopeMetrics, name string, value any) error {
+	switch v := value.(type) {
+	case bool:
+		addGaugeMetric(scopeMetrics, name, boolToFloat(v))
+	case float64:
+		addGaugeMetric(scopeMetrics, name, v)
+	case int64:
+		addCounterMetric(scopeMetrics, name, v)
+	case string:
+		addStringMetric(scopeMetrics, name, v)
+	case map[string]any:
+		// Handle complex metric types (distributions, rates, etc.)
+		return processComplexMetric(scopeMetrics, name, v)
+	case []any:
+		// Handle string lists
+		addStringListMetric(scopeMetrics, name, v)
+	}
+	return nil
+}
+
+// processDeclaredMetric converts a metric using its metrics.yaml definition.
+// It reports false when the declared type is not supported or the value does
+// not have the shape of the declared type, so the metric can be converted
+// from its JSON value instead.
+func processDeclaredMetric(scopeMetrics pmetric.ScopeMetrics, name string, definition metricDefinition, value any) (bool, error) {
+	if !matchesDeclaredType(definition.Type, value) {
+		return false, nil
+	}
+	first := scopeMetrics.Metrics().Len()
+
+	switch definition.Type {
+	case "counter":
+		addCounterMetric(scopeMetrics, name, toInt64(value))
+	case "quantity":
+		addGaugeMetric(scopeMetrics, name, toFloat64(value))
+	case "boolean":
+		b, _ := value.(bool)
+		addGaugeMetric(scopeMetrics, name, boolToFloat(b))
+	case "string", "text", "url", "uuid", "datetime":
+		s, _ := value.(string)
+		addStringMetric(scopeMetrics, name, s)
+	case "string_list":
+		values, _ := value.([]any)
+		addStringListMetric(scopeMetrics, name, values)
+	case "timespan":
+		data, _ := value.(map[string]any)
+		addGaugeMetric(scopeMetrics, name, toFloat64(data["value"]))
+	case "timing_distribution", "memory_distribution", "custom_distribution":
+		data, _ := value.(map[string]any)
+		if err := addDistributionMetric(scopeMetrics, name, data["sum"], data["values"]); err != nil {
+			return false, err
+		}
+	case "rate":
+		data, _ := value.(map[string]any)
+		if err := addRateMetric(scopeMetrics, name, data["numerator"], data["denominator"]); err != nil {
+			return false, err
+		}
+	case "labeled_counter":
+		labels, _ := value.(map[string]any)
+		addLabeledMetric(scopeMetrics, name, labels, true)
+	case "labeled_quantity", "labeled_boolean":
+		labels, _ := value.(map[string]any)
+		addLabeledMetric(scopeMetrics, name, labels, false)
+	default:
+		return false, nil
+	}
+
+	for i := first; i < scopeMetrics.Metrics().Len(); i++ {
+		metric := scopeMetrics.Metrics().At(i)
+		metric.SetDescription(definition.Description)
+		// Rates are ratios regardless of the declared unit
+		if definition.Type != "rate" {
+			metric.SetUnit(definition.otelUnit())
+		}
+	}
+
+	return true, nil
+}
+
+// matchesDeclaredType reports whether value has the JSON shape of the
+// declared metric type. Types the receiver does not convert match any value.
+func matchesDeclaredType(metricType string, value any) bool {
+	switch metricType {
+	case "counter", "quantity":
+		_, ok := value.(float64)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "string", "text", "url", "uuid", "datetime":
+		_, ok := value.(string)
+		return ok
+	case "string_list":
+		_, ok := value.([]any)
+		return ok
+	case "timespan":
+		data, ok := value.(map[string]any)
+		_, hasValue := data["value"]
+		return ok && hasValue
+	case "timing_distribution", "memory_distribution", "custom_distribution":
+		data, _ := value.(map[string]any)
+		_, valuesOK := data["values"].(map[string]any)
+		return valuesOK
+	case "rate":
+		data, _ := value.(map[string]any)
+		_, hasNum := data["numerator"]
+		_, hasDenom := data["denominator"]
+		return hasNum && hasDenom
+	case "labeled_counter", "labeled_quantity", "labeled_boolean":
+		_, ok := value.(map[string]any)
+		return ok
+	default:
+		return true
+	}
+}
+
+// flagUndeclaredMetrics marks the data points of metrics[first:] as not
+// declared in the registry
+func flagUndeclaredMetrics(metrics pmetric.MetricSlice, first int) {
+	forEachDataPointAttributes(metrics, first, func(attrs pcommon.Map) {
+		attrs.PutBool("glean.undeclared", true)
+	})
+}
+
 // processComplexMetric handles complex metric types like distributions and rates
 func processComplexMetric(scopeMetrics pmetric.ScopeMetrics, name string, data map[string]any) error {
 	// Check if it's a distribution (has "sum" and "values")
 	if sum, hasSum := data["sum"]; hasSum {
-		if values, hasValues := data["values"]; hasValues {
+		if values, hasValues := data["values"].(map[string]any); hasValues {
 			return addDistributionMetric(scopeMetrics, name, sum, values)
 		}
 	}
@@ -239,6 +667,43 @@ func addStringListMetric(scopeMetrics pmetric.ScopeMetrics, name string, values
 	}
 }
 
+// addLabeledMetric adds a labeled metric with one data point per label. Labeled
+// counters become a monotonic sum, other labeled metrics a gauge.
+func addLabeledMetric(scopeMetrics pmetric.ScopeMetrics, name string, values map[string]any, monotonic bool) {
+	metric := scopeMetrics.Metrics().AppendEmpty()
+	metric.SetName(name)
+	metric.SetUnit("1")
+
+	var dps pmetric.NumberDataPointSlice
+	if monotonic {
+		sum := metric.SetEmptySum()
+		sum.SetIsMonotonic(true)
+		sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+		dps = sum.DataPoints()
+	} else {
+		dps = metric.SetEmptyGauge().DataPoints()
+	}
+
+	// Sort labels for deterministic data point ordering
+	labels := make([]string, 0, len(values))
+	for label := range values {
+		labels = append(labels, label)
+	}
+	slices.Sort(labels)
+
+	for _, label := range labels {
+		dp := dps.AppendEmpty()
+		dp.SetTimestamp(pcommon.NewTimestampFromTime(time.Now()))
+		switch v := values[label].(type) {
+		case bool:
+			dp.SetIntValue(int64(boolToFloat(v)))
+		default:
+			dp.SetIntValue(toInt64(v))
+		}
+		dp.Attributes().PutStr("label", label)
+	}
+}
+
 // addDistributionMetric adds a distribution metric
 func addDistributionMetric(scopeMetrics pmetric.ScopeMetrics, name string, sum any, values any) error {
 	metric := scopeMetrics.Metrics().AppendEmpty()
@@ -329,6 +794,32 @@ func addRateMetric(scopeMetrics pmetric.ScopeMetrics, name string, numerator any
 
 // Helper functions
 
+// newTraceID derives a trace ID from seed, falling back to a random ID when
+// seed is empty
+func newTraceID(seed string) pcommon.TraceID {
+	var traceID pcommon.TraceID
+	if seed == "" {
+		_, _ = rand.Read(traceID[:])
+		return traceID
+	}
+	sum := sha256.Sum256([]byte(seed))
+	copy(traceID[:], sum[:])
+	return traceID
+}
+
+// newSpanID derives a span ID from seed and name, falling back to a random
+// ID when seed is empty
+func newSpanID(seed string, name string) pcommon.SpanID {
+	var spanID pcommon.SpanID
+	if seed == "" {
+		_, _ = rand.Read(spanID[:])
+		return spanID
+	}
+	sum := sha256.Sum256([]byte(seed + "/" + name))
+	copy(spanID[:], sum[:])
+	return spanID
+}
+
 func boolToFloat(b bool) float64 {
 	if b {
 		return 1.0
@@ -41,7 +41,7 @@ func TestConvertToMetrics(t *testing.T) {
 		},
 	}
 
-	metrics, err := convertToMetrics(ping)
+	metrics, err := convertToMetrics(ping, converterSettings{})
 	require.NoError(t, err)
 	assert.NotNil(t, metrics)
 
@@ -82,10 +82,111 @@ func TestConvertToMetrics(t *testing.T) {
 	assert.True(t, foundCounter)
 }
 
+func TestConvertToMetricsReplayed(t *testing.T) {
+	endTime := time.Date(2024, 1, 28, 10, 1, 0, 0, time.UTC)
+	ping := &GleanPing{
+		Request: GleanPingRequest{Replayed: true},
+		PingInfo: PingInfo{
+			StartTime: endTime.Add(-time.Minute),
+			EndTime:   endTime,
+			Experiments: map[string]Experiment{
+				"new-onboarding": {Branch: "treatment"},
+			},
+		},
+		Metrics: map[string]any{
+			"counter":  map[string]any{"test_counter": float64(5)},
+			"quantity": map[string]any{"test_quantity": float64(2)},
+		},
+	}
+
+	metrics, err := convertToMetrics(ping, converterSettings{})
+	require.NoError(t, err)
+
+	// Data points of replayed pings are placed at the end of the ping window
+	scopeMetrics := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+	require.Equal(t, 3, scopeMetrics.Metrics().Len())
+	for i := 0; i < scopeMetrics.Metrics().Len(); i++ {
+		metric := scopeMetrics.Metrics().At(i)
+		assert.Equal(t, endTime, metric.Gauge().DataPoints().At(0).Timestamp().AsTime(), metric.Name())
+	}
+}
+
+func TestConvertClientInfoAndExperiments(t *testing.T) {
+	ping := &GleanPing{
+		ClientInfo: ClientInfo{
+			ClientID:           "test-client-id",
+			AndroidSDKVersion:  "34",
+			WindowsBuildNumber: 22631,
+			BuildDate:          "2024-01-20T00:00:00+00:00",
+			FirstRunDate:       "2023-12-01+00:00",
+			Attribution: &ClientAttribution{
+				Source: "google-play",
+				Ext:    map[string]any{"experiment": "onboarding"},
+			},
+			Distribution: &ClientDistribution{Name: "partner"},
+		},
+		PingInfo: PingInfo{
+			Seq:      1,
+			PingType: "metrics",
+			Experiments: map[string]Experiment{
+				"new-onboarding": {Branch: "treatment", Extra: ExperimentExtra{Type: "nimbus-nimbus"}},
+				"dark-mode":      {Branch: "control"},
+			},
+		},
+		Metrics: map[string]any{},
+	}
+
+	metrics, err := convertToMetrics(ping, converterSettings{})
+	require.NoError(t, err)
+
+	rm := metrics.ResourceMetrics().At(0)
+	expectedResource := map[string]string{
+		"android.os.api_level":           "34",
+		"os.build_id":                    "22631",
+		"app.build_date":                 "2024-01-20T00:00:00+00:00",
+		"app.first_run_date":             "2023-12-01+00:00",
+		"app.attribution.source":         "google-play",
+		"app.attribution.ext.experiment": "onboarding",
+		"app.distribution.name":          "partner",
+	}
+	for key, expected := range expectedResource {
+		value, exists := rm.Resource().Attributes().Get(key)
+		assert.True(t, exists, key)
+		assert.Equal(t, expected, value.Str(), key)
+	}
+
+	scopeMetrics := rm.ScopeMetrics().At(0)
+	branch, exists := scopeMetrics.Scope().Attributes().Get("experiment.new-onboarding.branch")
+	assert.True(t, exists)
+	assert.Equal(t, "treatment", branch.Str())
+
+	// Enrollments are reported as a dedicated metric
+	require.Equal(t, 1, scopeMetrics.Metrics().Len())
+	enrollment := scopeMetrics.Metrics().At(0)
+	assert.Equal(t, "glean.experiment.enrollment", enrollment.Name())
+	require.Equal(t, 2, enrollment.Gauge().DataPoints().Len())
+
+	dp := enrollment.Gauge().DataPoints().At(1)
+	experimentID, _ := dp.Attributes().Get("experiment.id")
+	assert.Equal(t, "new-onboarding", experimentID.Str())
+	experimentBranch, _ := dp.Attributes().Get("experiment.branch")
+	assert.Equal(t, "treatment", experimentBranch.Str())
+	experimentType, _ := dp.Attributes().Get("experiment.type")
+	assert.Equal(t, "nimbus-nimbus", experimentType.Str())
+
+	// Enrollment IDs are unique per client and would make a series each
+	_, exists = dp.Attributes().Get("experiment.enrollment_id")
+	assert.False(t, exists)
+}
+
 func TestConvertToEventLogs(t *testing.T) {
 	startTime := time.Date(2024, 1, 28, 10, 0, 0, 0, time.UTC)
 
 	ping := &GleanPing{
+		Request: GleanPingRequest{
+			DocumentType: "events",
+			DocumentID:   "c641eacf-c30c-4171-b403-f077724e848a",
+		},
 		ClientInfo: ClientInfo{
 			ClientID: "test-client-id",
 		},
@@ -116,7 +217,7 @@ func TestConvertToEventLogs(t *testing.T) {
 		},
 	}
 
-	logs, err := convertToEventLogs(ping)
+	logs, err := convertToEventLogs(ping, converterSettings{})
 	require.NoError(t, err)
 	assert.NotNil(t, logs)
 
@@ -127,6 +228,8 @@ func TestConvertToEventLogs(t *testing.T) {
 	clientID, exists := attrs.Get("client.id")
 	assert.True(t, exists)
 	assert.Equal(t, "test-client-id", clientID.Str())
+	_, exists = attrs.Get("document.id")
+	assert.False(t, exists)
 
 	// Verify logs
 	scopeLogs := rl.ScopeLogs().At(0)
@@ -149,11 +252,158 @@ func TestConvertToEventLogs(t *testing.T) {
 	assert.True(t, exists)
 	assert.Equal(t, "submit", buttonID.Str())
 
+	// The document ID is unique per ping, so it is a record attribute
+	documentID, exists := log1.Attributes().Get("document.id")
+	assert.True(t, exists)
+	assert.Equal(t, "c641eacf-c30c-4171-b403-f077724e848a", documentID.Str())
+
 	// Verify timestamp (should be startTime + 1000ms)
 	expectedTimestamp := startTime.Add(1000 * time.Millisecond)
 	assert.Equal(t, expectedTimestamp.UnixNano(), log1.Timestamp().AsTime().UnixNano())
 }
 
+func TestConvertToTraces(t *testing.T) {
+	startTime := time.Date(2024, 1, 28, 10, 0, 0, 0, time.UTC)
+
+	ping := &GleanPing{
+		Request: GleanPingRequest{
+			Namespace:    "glean",
+			DocumentType: "metrics",
+			DocumentID:   "c641eacf-c30c-4171-b403-f077724e848a",
+		},
+		ClientInfo: ClientInfo{
+			ClientID: "test-client-id",
+		},
+		PingInfo: PingInfo{
+			Seq:       1,
+			StartTime: startTime,
+			EndTime:   startTime.Add(time.Minute),
+			PingType:  "metrics",
+		},
+		Metrics: map[string]any{
+			"timespan": map[string]any{
+				"app.startup": map[string]any{
+					"time_unit": "millisecond",
+					"value":     float64(1500),
+				},
+			},
+			"timing_distribution": map[string]any{
+				"app.page_load": map[string]any{
+					"sum": float64(2000000000),
+					"values": map[string]any{
+						"1000000000": float64(2),
+					},
+				},
+			},
+			"counter": map[string]any{
+				"app.opened": float64(5),
+			},
+		},
+	}
+
+	traces, err := convertToTraces(ping, converterSettings{})
+	require.NoError(t, err)
+
+	rs := traces.ResourceSpans().At(0)
+	clientID, exists := rs.Resource().Attributes().Get("client.id")
+	assert.True(t, exists)
+	assert.Equal(t, "test-client-id", clientID.Str())
+
+	scopeSpans := rs.ScopeSpans().At(0)
+	assert.Equal(t, "glean", scopeSpans.Scope().Name())
+	require.Equal(t, 3, scopeSpans.Spans().Len())
+
+	// Root span covers the ping lifecycle
+	root := scopeSpans.Spans().At(0)
+	assert.Equal(t, "ping.metrics", root.Name())
+	assert.True(t, root.ParentSpanID().IsEmpty())
+	assert.Equal(t, startTime.UnixNano(), root.StartTimestamp().AsTime().UnixNano())
+	assert.Equal(t, startTime.Add(time.Minute).UnixNano(), root.EndTimestamp().AsTime().UnixNano())
+	documentID, exists := root.Attributes().Get("document.id")
+	assert.True(t, exists)
+	assert.Equal(t, "c641eacf-c30c-4171-b403-f077724e848a", documentID.Str())
+	_, exists = rs.Resource().Attributes().Get("document.id")
+	assert.False(t, exists)
+
+	// Timespan child span
+	startup := scopeSpans.Spans().At(1)
+	assert.Equal(t, "timespan.app.startup", startup.Name())
+	assert.Equal(t, root.TraceID(), startup.TraceID())
+	assert.Equal(t, root.SpanID(), startup.ParentSpanID())
+	assert.Equal(t, startTime.Add(1500*time.Millisecond).UnixNano(), startup.EndTimestamp().AsTime().UnixNano())
+	_, exists = startup.Attributes().Get("document.id")
+	assert.True(t, exists)
+
+	// Timing distribution child span covers the accumulated sum
+	pageLoad := scopeSpans.Spans().At(2)
+	assert.Equal(t, "timing_distribution.app.page_load", pageLoad.Name())
+	assert.Equal(t, startTime.Add(2*time.Second).UnixNano(), pageLoad.EndTimestamp().AsTime().UnixNano())
+
+	// IDs are deterministic for a given document ID
+	again, err := convertToTraces(ping, converterSettings{})
+	require.NoError(t, err)
+	assert.Equal(t, root.TraceID(), again.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).TraceID())
+}
+
+func TestConvertToTracesMissingTimes(t *testing.T) {
+	submissionTime := time.Date(2024, 1, 28, 10, 0, 0, 0, time.UTC)
+	ping := &GleanPing{
+		Request: GleanPingRequest{
+			DocumentType:   "metrics",
+			DocumentID:     "c641eacf-c30c-4171-b403-f077724e848a",
+			SubmissionTime: submissionTime,
+		},
+		PingInfo: PingInfo{PingType: "metrics"},
+		Metrics: map[string]any{
+			"timespan": map[string]any{
+				"app.startup": map[string]any{
+					"time_unit": "millisecond",
+					"value":     float64(1500),
+				},
+			},
+		},
+	}
+
+	// Spans fall back to the submission time
+	traces, err := convertToTraces(ping, converterSettings{})
+	require.NoError(t, err)
+	spans := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	require.Equal(t, 2, spans.Len())
+	assert.Equal(t, submissionTime.UnixNano(), spans.At(0).StartTimestamp().AsTime().UnixNano())
+	assert.Equal(t, submissionTime.UnixNano(), spans.At(0).EndTimestamp().AsTime().UnixNano())
+	assert.Equal(t, submissionTime.Add(1500*time.Millisecond).UnixNano(), spans.At(1).EndTimestamp().AsTime().UnixNano())
+
+	// Without any time there is nothing to place the spans at
+	ping.Request.SubmissionTime = time.Time{}
+	traces, err = convertToTraces(ping, converterSettings{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, traces.SpanCount())
+}
+
+func TestTimespanDuration(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    any
+		expected time.Duration
+		ok       bool
+	}{
+		{"milliseconds", map[string]any{"time_unit": "millisecond", "value": float64(250)}, 250 * time.Millisecond, true},
+		{"seconds", map[string]any{"time_unit": "second", "value": float64(3)}, 3 * time.Second, true},
+		{"default unit", map[string]any{"value": float64(10)}, 10 * time.Millisecond, true},
+		{"unknown unit", map[string]any{"time_unit": "fortnight", "value": float64(1)}, 0, false},
+		{"missing value", map[string]any{"time_unit": "second"}, 0, false},
+		{"not an object", float64(1), 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			duration, ok := timespanDuration(tt.value)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, duration)
+		})
+	}
+}
+
 func TestConvertDistributionMetric(t *testing.T) {
 	ping := &GleanPing{
 		ClientInfo: ClientInfo{
@@ -181,7 +431,7 @@ func TestConvertDistributionMetric(t *testing.T) {
 		},
 	}
 
-	metrics, err := convertToMetrics(ping)
+	metrics, err := convertToMetrics(ping, converterSettings{})
 	require.NoError(t, err)
 
 	scopeMetrics := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
@@ -231,7 +481,7 @@ func TestDistributionMetricEdgeCases(t *testing.T) {
 			},
 		}
 
-		metrics, err := convertToMetrics(ping)
+		metrics, err := convertToMetrics(ping, converterSettings{})
 		require.NoError(t, err)
 
 		scopeMetrics := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
@@ -260,7 +510,7 @@ func TestDistributionMetricEdgeCases(t *testing.T) {
 			},
 		}
 
-		metrics, err := convertToMetrics(ping)
+		metrics, err := convertToMetrics(ping, converterSettings{})
 		require.NoError(t, err)
 
 		scopeMetrics := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
@@ -291,7 +541,7 @@ func TestDistributionMetricEdgeCases(t *testing.T) {
 			},
 		}
 
-		metrics, err := convertToMetrics(ping)
+		metrics, err := convertToMetrics(ping, converterSettings{})
 		require.NoError(t, err)
 
 		scopeMetrics := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
@@ -324,7 +574,7 @@ func TestConvertRateMetric(t *testing.T) {
 		},
 	}
 
-	metrics, err := convertToMetrics(ping)
+	metrics, err := convertToMetrics(ping, converterSettings{})
 	require.NoError(t, err)
 
 	scopeMetrics := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
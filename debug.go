@@ -0,0 +1,414 @@
+package gleanreceiver
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// debugIDHeader is the header Glean SDKs set when a ping is tagged for the
+// Debug Ping Viewer
+const debugIDHeader = "X-Debug-ID"
+
+// debugSubscriberBuffer is how many pings a live or tail subscriber may fall
+// behind before pings are dropped for it
+const debugSubscriberBuffer = 16
+
+//go:embed static/debug.html
+var debugPage []byte
+
+// debugPing is a ping captured for the debug view or live tail together
+// with what the receiver made of it
+type debugPing struct {
+	ReceivedAt      time.Time       `json:"received_at"`
+	DebugID         string          `json:"debug_id,omitempty"`
+	Namespace       string          `json:"namespace"`
+	DocumentType    string          `json:"document_type"`
+	DocumentVersion string          `json:"document_version"`
+	DocumentID      string          `json:"document_id"`
+	Status          int             `json:"status"`
+	Ping            json.RawMessage `json:"ping,omitempty"`
+	Metrics         json.RawMessage `json:"metrics,omitempty"`
+	Logs            json.RawMessage `json:"logs,omitempty"`
+	Traces          json.RawMessage `json:"traces,omitempty"`
+	Warnings        []string        `json:"warnings,omitempty"`
+
+	// clientKey is the client key of the ping, see clientKeyer. It is
+	// never served, only used to filter the tail and purge deleted clients.
+	clientKey string
+}
+
+// newDebugPing starts capturing a ping. debugID is empty for pings that are
+// only captured for the live tail.
+func newDebugPing(debugID string, gleanRequest GleanPingRequest) *debugPing {
+	return &debugPing{
+		ReceivedAt:      gleanRequest.SubmissionTime,
+		DebugID:         debugID,
+		Namespace:       gleanRequest.Namespace,
+		DocumentType:    gleanRequest.DocumentType,
+		DocumentVersion: gleanRequest.DocumentVersion,
+		DocumentID:      gleanRequest.DocumentID,
+		Status:          http.StatusOK,
+	}
+}
+
+// The capture methods below are no-ops on a nil *debugPing so the ping
+// handler can call them whether or not the ping is being captured.
+
+// setPing records the ping once privacy and redaction settings have been
+// applied, so the raw body is never kept, and the client key it belongs to
+func (p *debugPing) setPing(ping *GleanPing, clientKey string) {
+	if p == nil {
+		return
+	}
+	p.Ping, _ = json.Marshal(ping)
+	p.clientKey = clientKey
+}
+
+// warnf records a conversion warning
+func (p *debugPing) warnf(format string, args ...any) {
+	if p == nil {
+		return
+	}
+	p.Warnings = append(p.Warnings, fmt.Sprintf(format, args...))
+}
+
+// setMetrics records the converted metrics
+func (p *debugPing) setMetrics(metrics pmetric.Metrics) {
+	if p == nil {
+		return
+	}
+	p.Metrics, _ = (&pmetric.JSONMarshaler{}).MarshalMetrics(metrics)
+}
+
+// setLogs records the converted logs
+func (p *debugPing) setLogs(logs plog.Logs) {
+	if p == nil {
+		return
+	}
+	p.Logs, _ = (&plog.JSONMarshaler{}).MarshalLogs(logs)
+}
+
+// setTraces records the converted traces
+func (p *debugPing) setTraces(traces ptrace.Traces) {
+	if p == nil {
+		return
+	}
+	p.Traces, _ = (&ptrace.JSONMarshaler{}).MarshalTraces(traces)
+}
+
+// debugSubscriber receives captured pings matching its filter as they arrive
+type debugSubscriber struct {
+	matches func(*debugPing) bool
+	pings   chan *debugPing
+
+	// untagged is set for subscribers that want pings without a debug ID
+	untagged bool
+
+	// dropped counts pings not delivered because the buffer was full
+	dropped atomic.Int64
+}
+
+// debugView keeps the latest pings per debug ID in memory and serves them
+// through a local UI, a JSON API and a Server-Sent Events stream
+type debugView struct {
+	path          string
+	maxPingsPerID int
+	maxDebugIDs   int
+	clientKeys    *clientKeyer
+
+	mu          sync.Mutex
+	pings       map[string][]*debugPing
+	lastSeen    map[string]time.Time
+	subscribers map[*debugSubscriber]struct{}
+	tailers     int
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newDebugView creates a debug view, or returns nil if it is disabled.
+// clientKeys derives the keys the tail filters clients by.
+func newDebugView(cfg DebugConfig, clientKeys *clientKeyer) *debugView {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &debugView{
+		path:          strings.TrimSuffix(cfg.Path, "/"),
+		maxPingsPerID: cfg.MaxPingsPerID,
+		maxDebugIDs:   cfg.MaxDebugIDs,
+		clientKeys:    clientKeys,
+		pings:         make(map[string][]*debugPing),
+		lastSeen:      make(map[string]time.Time),
+		subscribers:   make(map[*debugSubscriber]struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+// record stores a captured ping tagged with a debug ID and sends every
+// captured ping to live subscribers
+func (v *debugView) record(p *debugPing) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if p.DebugID != "" {
+		v.store(p)
+	}
+
+	for subscriber := range v.subscribers {
+		if !subscriber.matches(p) {
+			continue
+		}
+		// Never block the ping path on a slow subscriber
+		select {
+		case subscriber.pings <- p:
+		default:
+			subscriber.dropped.Add(1)
+		}
+	}
+}
+
+// store keeps p in the history of its debug ID. v.mu must be held.
+func (v *debugView) store(p *debugPing) {
+	pings := append(v.pings[p.DebugID], p)
+	if len(pings) > v.maxPingsPerID {
+		pings = pings[len(pings)-v.maxPingsPerID:]
+	}
+	v.pings[p.DebugID] = pings
+	v.lastSeen[p.DebugID] = p.ReceivedAt
+
+	// Evict the least recently seen debug ID once over the limit
+	if len(v.pings) > v.maxDebugIDs {
+		oldest := ""
+		for debugID, seen := range v.lastSeen {
+			if oldest == "" || seen.Before(v.lastSeen[oldest]) {
+				oldest = debugID
+			}
+		}
+		delete(v.pings, oldest)
+		delete(v.lastSeen, oldest)
+	}
+}
+
+// purgeClient drops the kept pings of the client with clientKey and returns
+// how many were dropped
+func (v *debugView) purgeClient(clientKey string) int {
+	if clientKey == "" {
+		return 0
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	purged := 0
+	for debugID, pings := range v.pings {
+		kept := pings[:0]
+		for _, p := range pings {
+			if p.clientKey == clientKey {
+				purged++
+				continue
+			}
+			kept = append(kept, p)
+		}
+		if len(kept) == 0 {
+			delete(v.pings, debugID)
+			delete(v.lastSeen, debugID)
+			continue
+		}
+		v.pings[debugID] = kept
+	}
+	return purged
+}
+
+// tailing reports whether a subscriber wants untagged pings, in which case
+// every ping is captured
+func (v *debugView) tailing() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.tailers > 0
+}
+
+// subscribe registers a live subscriber for pings matching filter. Untagged
+// pings are only captured while a subscriber asks for them.
+func (v *debugView) subscribe(matches func(*debugPing) bool, untagged bool) *debugSubscriber {
+	subscriber := &debugSubscriber{
+		matches:  matches,
+		pings:    make(chan *debugPing, debugSubscriberBuffer),
+		untagged: untagged,
+	}
+	v.mu.Lock()
+	v.subscribers[subscriber] = struct{}{}
+	if untagged {
+		v.tailers++
+	}
+	v.mu.Unlock()
+	return subscriber
+}
+
+// unsubscribe removes a live subscriber
+func (v *debugView) unsubscribe(subscriber *debugSubscriber) {
+	v.mu.Lock()
+	if _, ok := v.subscribers[subscriber]; ok && subscriber.untagged {
+		v.tailers--
+	}
+	delete(v.subscribers, subscriber)
+	v.mu.Unlock()
+}
+
+// close ends every live stream so the HTTP server can shut down
+func (v *debugView) close() {
+	v.closeOnce.Do(func() { close(v.done) })
+}
+
+// debugIDSummary describes the captured pings of one debug ID
+type debugIDSummary struct {
+	DebugID  string    `json:"debug_id"`
+	Pings    int       `json:"pings"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// debugIDs returns the known debug IDs, most recently seen first
+func (v *debugView) debugIDs() []debugIDSummary {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	summaries := make([]debugIDSummary, 0, len(v.pings))
+	for debugID, pings := range v.pings {
+		summaries = append(summaries, debugIDSummary{
+			DebugID:  debugID,
+			Pings:    len(pings),
+			LastSeen: v.lastSeen[debugID],
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].LastSeen.After(summaries[j].LastSeen)
+	})
+	return summaries
+}
+
+// pingsFor returns the captured pings of debugID, newest first
+func (v *debugView) pingsFor(debugID string) []*debugPing {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	pings := v.pings[debugID]
+	newestFirst := make([]*debugPing, len(pings))
+	for i, p := range pings {
+		newestFirst[len(pings)-1-i] = p
+	}
+	return newestFirst
+}
+
+// register adds the debug UI, API and live tail routes to mux. The mux is
+// served on the debug endpoint, never on the ingestion endpoint.
+func (v *debugView) register(mux *http.ServeMux) {
+	mux.HandleFunc("GET "+v.path+"/{$}", v.handlePage)
+	mux.HandleFunc("GET "+v.path+"/api/debug-ids", v.handleDebugIDs)
+	mux.HandleFunc("GET "+v.path+"/api/pings", v.handlePings)
+	mux.HandleFunc("GET "+v.path+"/api/stream", v.handleStream)
+	mux.HandleFunc("GET "+v.path+"/tail", v.handleTail)
+}
+
+// handlePage serves the debug UI
+func (v *debugView) handlePage(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(debugPage)
+}
+
+// handleDebugIDs lists the debug IDs with captured pings
+func (v *debugView) handleDebugIDs(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, v.debugIDs())
+}
+
+// handlePings lists the captured pings of the debug_id query parameter
+func (v *debugView) handlePings(w http.ResponseWriter, req *http.Request) {
+	debugID := req.URL.Query().Get("debug_id")
+	if debugID == "" {
+		http.Error(w, "Missing debug_id parameter", http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, v.pingsFor(debugID))
+}
+
+// handleStream streams pings of the debug_id query parameter as
+// Server-Sent Events as they arrive. Without debug_id every tagged ping
+// is streamed.
+func (v *debugView) handleStream(w http.ResponseWriter, req *http.Request) {
+	debugID := req.URL.Query().Get("debug_id")
+	v.stream(w, req, false, func(p *debugPing) bool {
+		return p.DebugID != "" && (debugID == "" || p.DebugID == debugID)
+	})
+}
+
+// handleTail streams every received ping as Server-Sent Events. The
+// namespace, document_type, debug_id and client_id query parameters narrow
+// the stream. client_id is the raw identifier the SDK sent; it is compared
+// by client key, so the filter still matches when client_id is hashed or
+// dropped from the captured ping.
+func (v *debugView) handleTail(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	namespace := query.Get("namespace")
+	documentType := query.Get("document_type")
+	debugID := query.Get("debug_id")
+	clientKey := v.clientKeys.key(query.Get("client_id"))
+
+	v.stream(w, req, true, func(p *debugPing) bool {
+		return (namespace == "" || p.Namespace == namespace) &&
+			(documentType == "" || p.DocumentType == documentType) &&
+			(debugID == "" || p.DebugID == debugID) &&
+			(clientKey == "" || p.clientKey == clientKey)
+	})
+}
+
+// stream writes the pings matching filter to w as Server-Sent Events until
+// the client disconnects or the receiver shuts down. Pings dropped because
+// the client fell behind are reported in a "dropped" event.
+func (v *debugView) stream(w http.ResponseWriter, req *http.Request, untagged bool, matches func(*debugPing) bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	subscriber := v.subscribe(matches, untagged)
+	defer v.unsubscribe(subscriber)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case p := <-subscriber.pings:
+			data, err := json.Marshal(p)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: ping\ndata: %s\n\n", data)
+			if dropped := subscriber.dropped.Swap(0); dropped > 0 {
+				fmt.Fprintf(w, "event: dropped\ndata: {\"dropped\":%d}\n\n", dropped)
+			}
+			flusher.Flush()
+		case <-req.Context().Done():
+			return
+		case <-v.done:
+			return
+		}
+	}
+}
+
+// writeJSON writes value as a JSON response
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
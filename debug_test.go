@@ -0,0 +1,294 @@
+package gleanreceiver
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDebugView(maxPingsPerID int, maxDebugIDs int) *debugView {
+	return newDebugView(DebugConfig{
+		Enabled:       true,
+		Endpoint:      "localhost:9890",
+		Path:          "/debug",
+		MaxPingsPerID: maxPingsPerID,
+		MaxDebugIDs:   maxDebugIDs,
+	}, newClientKeyer())
+}
+
+func newTestDebugPing(debugID string, documentID string, receivedAt time.Time) *debugPing {
+	return newDebugPing(debugID, GleanPingRequest{
+		Namespace:      "test-app",
+		DocumentType:   "metrics",
+		DocumentID:     documentID,
+		SubmissionTime: receivedAt,
+	})
+}
+
+func TestNewDebugViewDisabled(t *testing.T) {
+	assert.Nil(t, newDebugView(DebugConfig{}, newClientKeyer()))
+}
+
+func TestDebugViewRecord(t *testing.T) {
+	view := newTestDebugView(2, 2)
+	start := time.Date(2024, 1, 28, 10, 0, 0, 0, time.UTC)
+
+	view.record(newTestDebugPing("alice", "doc-1", start))
+	view.record(newTestDebugPing("alice", "doc-2", start.Add(time.Second)))
+	view.record(newTestDebugPing("alice", "doc-3", start.Add(2*time.Second)))
+
+	// Only the latest pings are kept, newest first
+	pings := view.pingsFor("alice")
+	require.Len(t, pings, 2)
+	assert.Equal(t, "doc-3", pings[0].DocumentID)
+	assert.Equal(t, "doc-2", pings[1].DocumentID)
+
+	// The least recently seen debug ID is evicted
+	view.record(newTestDebugPing("bob", "doc-4", start.Add(3*time.Second)))
+	view.record(newTestDebugPing("carol", "doc-5", start.Add(4*time.Second)))
+	ids := view.debugIDs()
+	require.Len(t, ids, 2)
+	assert.Equal(t, "carol", ids[0].DebugID)
+	assert.Equal(t, "bob", ids[1].DebugID)
+	assert.Empty(t, view.pingsFor("alice"))
+}
+
+func TestDebugViewPurgeClient(t *testing.T) {
+	view := newTestDebugView(10, 10)
+	keys := newClientKeyer()
+	start := time.Date(2024, 1, 28, 10, 0, 0, 0, time.UTC)
+
+	deleted := newTestDebugPing("alice", "doc-1", start)
+	deleted.setPing(&GleanPing{}, keys.key("deleted-client"))
+	kept := newTestDebugPing("alice", "doc-2", start.Add(time.Second))
+	kept.setPing(&GleanPing{}, keys.key("other-client"))
+	onlyDeleted := newTestDebugPing("bob", "doc-3", start.Add(2*time.Second))
+	onlyDeleted.setPing(&GleanPing{}, keys.key("deleted-client"))
+	for _, p := range []*debugPing{deleted, kept, onlyDeleted} {
+		view.record(p)
+	}
+
+	assert.Equal(t, 2, view.purgeClient(keys.key("deleted-client")))
+	pings := view.pingsFor("alice")
+	require.Len(t, pings, 1)
+	assert.Equal(t, "doc-2", pings[0].DocumentID)
+
+	// Debug IDs left without pings are dropped
+	ids := view.debugIDs()
+	require.Len(t, ids, 1)
+	assert.Equal(t, "alice", ids[0].DebugID)
+
+	assert.Zero(t, view.purgeClient(""))
+}
+
+func TestDebugViewSubscriberDropsWhenBehind(t *testing.T) {
+	view := newTestDebugView(100, 10)
+	subscriber := view.subscribe(func(p *debugPing) bool { return p.DebugID == "alice" }, false)
+
+	for i := 0; i < debugSubscriberBuffer+5; i++ {
+		view.record(newTestDebugPing("alice", "doc", time.Now()))
+	}
+	view.record(newTestDebugPing("bob", "doc", time.Now()))
+
+	// Recording never blocks and the buffer holds at most its capacity
+	assert.Len(t, subscriber.pings, debugSubscriberBuffer)
+
+	view.unsubscribe(subscriber)
+	view.record(newTestDebugPing("alice", "doc", time.Now()))
+	assert.Len(t, subscriber.pings, debugSubscriberBuffer)
+}
+
+func TestDebugPingCapture(t *testing.T) {
+	p := newTestDebugPing("alice", "doc-1", time.Now())
+	p.setPing(&GleanPing{ClientInfo: ClientInfo{ClientID: "pseudonym"}}, "client-key")
+	assert.Contains(t, string(p.Ping), `"client_id":"pseudonym"`)
+	assert.Equal(t, "client-key", p.clientKey)
+
+	// The client key is never served
+	data, err := json.Marshal(p)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "client-key")
+
+	p.warnf("metric %s is not declared", "app.opened")
+	assert.Equal(t, []string{"metric app.opened is not declared"}, p.Warnings)
+
+	// Capture methods are no-ops for pings that are not captured
+	var uncaptured *debugPing
+	uncaptured.setPing(&GleanPing{}, "client-key")
+	uncaptured.warnf("ignored")
+}
+
+func TestDebugViewHandlers(t *testing.T) {
+	view := newTestDebugView(10, 10)
+	view.record(newTestDebugPing("alice", "doc-1", time.Now()))
+
+	mux := http.NewServeMux()
+	view.register(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/debug/")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+
+	resp, err = http.Get(server.URL + "/debug/api/debug-ids")
+	require.NoError(t, err)
+	var ids []debugIDSummary
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&ids))
+	resp.Body.Close()
+	require.Len(t, ids, 1)
+	assert.Equal(t, "alice", ids[0].DebugID)
+	assert.Equal(t, 1, ids[0].Pings)
+
+	resp, err = http.Get(server.URL + "/debug/api/pings?debug_id=alice")
+	require.NoError(t, err)
+	var pings []debugPing
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&pings))
+	resp.Body.Close()
+	require.Len(t, pings, 1)
+	assert.Equal(t, "doc-1", pings[0].DocumentID)
+
+	resp, err = http.Get(server.URL + "/debug/api/pings")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestDebugViewStream(t *testing.T) {
+	view := newTestDebugView(10, 10)
+
+	mux := http.NewServeMux()
+	view.register(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	defer view.close()
+
+	resp, err := http.Get(server.URL + "/debug/api/stream?debug_id=alice")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// The subscription is registered before the headers are flushed
+	view.record(newTestDebugPing("bob", "doc-1", time.Now()))
+	view.record(newTestDebugPing("alice", "doc-2", time.Now()))
+
+	reader := bufio.NewReader(resp.Body)
+	event, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event: ping\n", event)
+	data, err := reader.ReadString('\n')
+	require.NoError(t, err)
+
+	var p debugPing
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(data, "data: ")), &p))
+	assert.Equal(t, "alice", p.DebugID)
+	assert.Equal(t, "doc-2", p.DocumentID)
+}
+
+func TestDebugViewTailing(t *testing.T) {
+	view := newTestDebugView(10, 10)
+
+	// Debug ID streams do not capture untagged pings
+	live := view.subscribe(func(*debugPing) bool { return true }, false)
+	assert.False(t, view.tailing())
+
+	tail := view.subscribe(func(*debugPing) bool { return true }, true)
+	assert.True(t, view.tailing())
+
+	// Untagged pings reach subscribers but are not kept
+	view.record(newTestDebugPing("", "doc-1", time.Now()))
+	assert.Len(t, tail.pings, 1)
+	assert.Empty(t, view.debugIDs())
+
+	view.unsubscribe(tail)
+	view.unsubscribe(tail)
+	assert.False(t, view.tailing())
+	view.unsubscribe(live)
+}
+
+func TestDebugViewTail(t *testing.T) {
+	view := newTestDebugView(10, 10)
+
+	mux := http.NewServeMux()
+	view.register(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	defer view.close()
+
+	keys := view.clientKeys
+	matching := newTestDebugPing("", "doc-match", time.Now())
+	matching.setPing(&GleanPing{}, keys.key("c641eacf-c30c-4171-b403-f077724e848a"))
+
+	resp, err := http.Get(server.URL + "/debug/tail?namespace=test-app&document_type=metrics&client_id=c641eacf-c30c-4171-b403-f077724e848a")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	require.True(t, view.tailing())
+
+	other := newTestDebugPing("", "doc-other-client", time.Now())
+	other.setPing(&GleanPing{}, keys.key("another-client"))
+	view.record(other)
+	noClient := newTestDebugPing("", "doc-no-client", time.Now())
+	view.record(noClient)
+	wrongType := newTestDebugPing("", "doc-events", time.Now())
+	wrongType.DocumentType = "events"
+	wrongType.setPing(&GleanPing{}, keys.key("c641eacf-c30c-4171-b403-f077724e848a"))
+	view.record(wrongType)
+	view.record(matching)
+
+	reader := bufio.NewReader(resp.Body)
+	event, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event: ping\n", event)
+	data, err := reader.ReadString('\n')
+	require.NoError(t, err)
+
+	var p debugPing
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(data, "data: ")), &p))
+	assert.Equal(t, "doc-match", p.DocumentID)
+}
+
+func TestDebugViewTailReportsDropped(t *testing.T) {
+	view := newTestDebugView(10, 10)
+
+	mux := http.NewServeMux()
+	view.register(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	defer view.close()
+
+	resp, err := http.Get(server.URL + "/debug/tail")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	// Publish far more pings than the subscriber buffer holds before the
+	// client reads any of them
+	for i := 0; i < 10*debugSubscriberBuffer; i++ {
+		view.record(newTestDebugPing("", "doc", time.Now()))
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if line == "event: dropped\n" {
+			data, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			var dropped struct {
+				Dropped int `json:"dropped"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(data, "data: ")), &dropped))
+			assert.Positive(t, dropped.Dropped)
+			return
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package gleanreceiver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// gzipMagic starts every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// envelopeAttributes are the attributes of a decoded/raw sink export that
+// are not request headers
+var envelopeAttributes = map[string]bool{
+	"uri":                  true,
+	"submission_timestamp": true,
+	"args":                 true,
+	"protocol":             true,
+	"method":               true,
+	"remote_addr":          true,
+}
+
+// pingEnvelope wraps a raw ping written by an edge server, as found in
+// NDJSON archives and on message queues. Envelopes either carry the uri,
+// headers and submission_timestamp directly or in the attributeMap of
+// Mozilla's decoded/raw sink exports, where header names are snake_case.
+type pingEnvelope struct {
+	URI                 string            `json:"uri"`
+	Headers             map[string]string `json:"headers"`
+	SubmissionTimestamp string            `json:"submission_timestamp"`
+	AttributeMap        map[string]string `json:"attributeMap"`
+
+	// Payload is the ping body, as a JSON object or a base64 string of the
+	// possibly gzip-compressed body
+	Payload json.RawMessage `json:"payload"`
+}
+
+// parsePingEnvelope parses an envelope into a ping request and its body. The
+// submission time is left zero when the envelope does not carry one. Gzip
+// payloads are refused once they decompress to more than maxDecompressed
+// bytes, as HTTP pings are.
+func parsePingEnvelope(data []byte, maxDecompressed int64) (GleanPingRequest, []byte, error) {
+	var envelope pingEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return GleanPingRequest{}, nil, fmt.Errorf("invalid envelope: %w", err)
+	}
+
+	uri := envelope.URI
+	submissionTimestamp := envelope.SubmissionTimestamp
+	headers := http.Header{}
+	for name, value := range envelope.AttributeMap {
+		switch {
+		case name == "uri" && uri == "":
+			uri = value
+		case name == "submission_timestamp" && submissionTimestamp == "":
+			submissionTimestamp = value
+		case !envelopeAttributes[name]:
+			headers.Set(strings.ReplaceAll(name, "_", "-"), value)
+		}
+	}
+	for name, value := range envelope.Headers {
+		headers.Set(name, value)
+	}
+
+	gleanRequest, err := parseSubmissionPath(uri)
+	if err != nil {
+		return GleanPingRequest{}, nil, err
+	}
+	gleanRequest.Headers = headers
+
+	if submissionTimestamp != "" {
+		gleanRequest.SubmissionTime, err = time.Parse(time.RFC3339Nano, submissionTimestamp)
+		if err != nil {
+			return GleanPingRequest{}, nil, fmt.Errorf("invalid submission_timestamp: %w", err)
+		}
+	}
+
+	body, err := decodeEnvelopePayload(envelope.Payload, maxDecompressed)
+	if err != nil {
+		return GleanPingRequest{}, nil, err
+	}
+	return gleanRequest, body, nil
+}
+
+// decodeEnvelopePayload returns the ping body held by an envelope payload,
+// decompressing gzip bodies up to maxDecompressed bytes
+func decodeEnvelopePayload(payload json.RawMessage, maxDecompressed int64) ([]byte, error) {
+	payload = bytes.TrimSpace(payload)
+	if len(payload) == 0 || bytes.Equal(payload, []byte("null")) {
+		return nil, errors.New("missing payload")
+	}
+	if payload[0] != '"' {
+		return payload, nil
+	}
+
+	var encoded string
+	if err := json.Unmarshal(payload, &encoded); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+	body, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		// Some exports keep the JSON body as a plain string
+		body = []byte(encoded)
+	}
+
+	if !bytes.HasPrefix(body, gzipMagic) {
+		return body, nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip payload: %w", err)
+	}
+	defer reader.Close()
+	body, err = io.ReadAll(io.LimitReader(reader, maxDecompressed+1))
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip payload: %w", err)
+	}
+	if int64(len(body)) > maxDecompressed {
+		return nil, errDecompressedTooLarge
+	}
+	return body, nil
+}
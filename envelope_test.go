@@ -0,0 +1,108 @@
+package gleanreceiver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const envelopePingBody = `{"ping_info":{"seq":1,"start_time":"2024-01-28T10:00:00Z","end_time":"2024-01-28T10:01:00Z"},"client_info":{"client_id":"test-client"},"metrics":{"counter":{"test_counter":5}}}`
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, err := writer.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestParsePingEnvelope(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte(envelopePingBody))
+	compressed := base64.StdEncoding.EncodeToString(gzipBytes(t, []byte(envelopePingBody)))
+
+	tests := []struct {
+		name            string
+		line            string
+		expectedHeaders http.Header
+		expectedTime    time.Time
+		expectError     bool
+	}{
+		{
+			name:            "envelope with JSON payload",
+			line:            `{"uri":"/submit/test-app/metrics/1/doc-1","headers":{"X-Debug-ID":"qa"},"submission_timestamp":"2024-01-28T10:02:00Z","payload":` + envelopePingBody + `}`,
+			expectedHeaders: http.Header{"X-Debug-Id": {"qa"}},
+			expectedTime:    time.Date(2024, 1, 28, 10, 2, 0, 0, time.UTC),
+		},
+		{
+			name:            "envelope with base64 payload",
+			line:            `{"uri":"/submit/test-app/metrics/1/doc-1","submission_timestamp":"2024-01-28T10:02:00Z","payload":"` + encoded + `"}`,
+			expectedHeaders: http.Header{},
+			expectedTime:    time.Date(2024, 1, 28, 10, 2, 0, 0, time.UTC),
+		},
+		{
+			name: "raw sink export with gzip payload",
+			line: `{"attributeMap":{"uri":"/submit/test-app/metrics/1/doc-1","submission_timestamp":"2024-01-28T10:02:00Z","x_debug_id":"qa","x_source_tags":"automation","remote_addr":"127.0.0.1"},"payload":"` + compressed + `"}`,
+			expectedHeaders: http.Header{
+				"X-Debug-Id":    {"qa"},
+				"X-Source-Tags": {"automation"},
+			},
+			expectedTime: time.Date(2024, 1, 28, 10, 2, 0, 0, time.UTC),
+		},
+		{
+			name:        "missing payload",
+			line:        `{"uri":"/submit/test-app/metrics/1/doc-1"}`,
+			expectError: true,
+		},
+		{
+			name:        "missing uri",
+			line:        `{"payload":` + envelopePingBody + `}`,
+			expectError: true,
+		},
+		{
+			name:        "invalid submission timestamp",
+			line:        `{"uri":"/submit/test-app/metrics/1/doc-1","submission_timestamp":"yesterday","payload":` + envelopePingBody + `}`,
+			expectError: true,
+		},
+		{
+			name:        "invalid envelope",
+			line:        `not json`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gleanRequest, body, err := parsePingEnvelope([]byte(tt.line), defaultMaxRequestBodySize)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "test-app", gleanRequest.Namespace)
+			assert.Equal(t, "doc-1", gleanRequest.DocumentID)
+			assert.Equal(t, tt.expectedHeaders, gleanRequest.Headers)
+			assert.True(t, tt.expectedTime.Equal(gleanRequest.SubmissionTime))
+			assert.JSONEq(t, envelopePingBody, string(body))
+		})
+	}
+}
+
+func TestParsePingEnvelopeDecompressedLimit(t *testing.T) {
+	compressed := base64.StdEncoding.EncodeToString(gzipBytes(t, []byte(envelopePingBody)))
+	line := []byte(`{"uri":"/submit/test-app/metrics/1/doc-1","payload":"` + compressed + `"}`)
+
+	_, body, err := parsePingEnvelope(line, int64(len(envelopePingBody)))
+	require.NoError(t, err)
+	assert.JSONEq(t, envelopePingBody, string(body))
+
+	// A payload decompressing past the limit is refused, not buffered
+	_, _, err = parsePingEnvelope(line, int64(len(envelopePingBody))-1)
+	assert.ErrorIs(t, err, errDecompressedTooLarge)
+}
@@ -6,7 +6,9 @@ import (
 	"time"
 
 	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configgrpc"
 	"go.opentelemetry.io/collector/config/confighttp"
+	"go.opentelemetry.io/collector/config/configoptional"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/receiver"
 )
@@ -28,6 +30,7 @@ func NewFactory() receiver.Factory {
 		createDefaultConfig,
 		receiver.WithMetrics(createMetricsReceiver, stability),
 		receiver.WithLogs(createLogsReceiver, stability),
+		receiver.WithTraces(createTracesReceiver, stability),
 	)
 }
 
@@ -37,9 +40,51 @@ func createDefaultConfig() component.Config {
 	serverConfig.NetAddr.Endpoint = "localhost:9888"
 	serverConfig.ReadHeaderTimeout = 20 * time.Second
 
+	grpcConfig := configgrpc.NewDefaultServerConfig()
+	grpcConfig.NetAddr.Endpoint = "localhost:9889"
+
 	return &Config{
 		ServerConfig: serverConfig,
 		Path:         "/submit/{namespace}/{document_type}/{document_version}/{document_id}",
+		RetryAfter:   time.Minute,
+		Sessions: SessionsConfig{
+			IdleTimeout: 30 * time.Minute,
+			MaxSessions: 100000,
+		},
+		Registry: RegistryConfig{
+			UndeclaredMetrics: undeclaredAccept,
+			UndeclaredPings:   undeclaredAccept,
+		},
+		SchemaValidation: SchemaValidationConfig{
+			OnFailure: validationFailureReject,
+		},
+		Privacy: PrivacyConfig{
+			IdentifierMode: identifierModeNone,
+			TruncateLength: 8,
+		},
+		RequestHeaders: []string{"X-Debug-ID", "X-Source-Tags", "X-Telemetry-Agent", "User-Agent"},
+		Limits: LimitsConfig{
+			Action: limitActionTruncate,
+		},
+		RateLimit: RateLimitConfig{
+			TrustedProxies: 1,
+			MaxKeys:        10000,
+		},
+		PendingPings: PendingPingsConfig{
+			PollInterval: 10 * time.Second,
+		},
+		GRPC: configoptional.Default(grpcConfig),
+		Kafka: KafkaConfig{
+			GroupID:       "glean-receiver",
+			InitialOffset: kafkaOffsetLatest,
+			RetryBackoff:  time.Second,
+		},
+		Debug: DebugConfig{
+			Endpoint:      "localhost:9890",
+			Path:          "/debug",
+			MaxPingsPerID: 20,
+			MaxDebugIDs:   100,
+		},
 	}
 }
 
@@ -50,7 +95,7 @@ func createMetricsReceiver(
 	cfg component.Config,
 	consumer consumer.Metrics,
 ) (receiver.Metrics, error) {
-	return getOrCreateReceiver(set, cfg, consumer, nil)
+	return getOrCreateReceiver(set, cfg, consumer, nil, nil)
 }
 
 // createLogsReceiver creates a logs receiver based on provided config
@@ -60,7 +105,17 @@ func createLogsReceiver(
 	cfg component.Config,
 	consumer consumer.Logs,
 ) (receiver.Logs, error) {
-	return getOrCreateReceiver(set, cfg, nil, consumer)
+	return getOrCreateReceiver(set, cfg, nil, consumer, nil)
+}
+
+// createTracesReceiver creates a traces receiver based on provided config
+func createTracesReceiver(
+	ctx context.Context,
+	set receiver.Settings,
+	cfg component.Config,
+	consumer consumer.Traces,
+) (receiver.Traces, error) {
+	return getOrCreateReceiver(set, cfg, nil, nil, consumer)
 }
 
 // getOrCreateReceiver returns a shared receiver instance
@@ -69,6 +124,7 @@ func getOrCreateReceiver(
 	cfg component.Config,
 	metricsConsumer consumer.Metrics,
 	logsConsumer consumer.Logs,
+	tracesConsumer consumer.Traces,
 ) (*gleanReceiver, error) {
 	receiversMux.Lock()
 	defer receiversMux.Unlock()
@@ -85,11 +141,14 @@ func getOrCreateReceiver(
 		if logsConsumer != nil {
 			rcvr.logsConsumer = logsConsumer
 		}
+		if tracesConsumer != nil {
+			rcvr.tracesConsumer = tracesConsumer
+		}
 		return rcvr, nil
 	}
 
 	// Create new receiver
-	rcvr, err := newGleanReceiver(rCfg, set, metricsConsumer, logsConsumer)
+	rcvr, err := newGleanReceiver(rCfg, set, metricsConsumer, logsConsumer, tracesConsumer)
 	if err != nil {
 		return nil, err
 	}
@@ -48,6 +48,21 @@ func TestCreateLogsReceiver(t *testing.T) {
 	assert.NotNil(t, receiver)
 }
 
+func TestCreateTracesReceiver(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig()
+
+	receiver, err := factory.CreateTraces(
+		context.Background(),
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		cfg,
+		consumertest.NewNop(),
+	)
+
+	require.NoError(t, err)
+	assert.NotNil(t, receiver)
+}
+
 func TestCreateReceiverWithInvalidConfig(t *testing.T) {
 	factory := NewFactory()
 	cfg := &Config{
@@ -16,6 +16,7 @@ import (
 
 type gleanPingForwarder struct {
 	cfg    *Config
+	url    string
 	logger *zap.Logger
 	host   component.Host
 	client *http.Client
@@ -32,14 +33,26 @@ func newGleanPingForwarder(cfg *Config, set receiver.Settings) (*gleanPingForwar
 
 	return &gleanPingForwarder{
 		cfg:    cfg,
+		url:    cfg.ForwardURL,
 		logger: set.Logger,
 		client: client,
 	}, nil
 }
 
+// newDeletionRequestForwarder creates a gleanPingForwarder that sends
+// deletion-request pings to the dedicated deletion endpoint
+func newDeletionRequestForwarder(cfg *Config, set receiver.Settings) (*gleanPingForwarder, error) {
+	forwarder, err := newGleanPingForwarder(cfg, set)
+	if err != nil {
+		return nil, err
+	}
+	forwarder.url = cfg.DeletionRequest.ForwardURL
+	return forwarder, nil
+}
+
 // forwardRawPing forwards the raw Glean ping JSON to the configured downstream endpoint
 func (r *gleanPingForwarder) forwardRawPing(ctx context.Context, gleanReq GleanPingRequest, body []byte) error {
-	if r.cfg.ForwardURL == "" {
+	if r.url == "" {
 		return nil // Forwarding not configured, skip
 	}
 
@@ -53,7 +66,7 @@ func (r *gleanPingForwarder) forwardRawPing(ctx context.Context, gleanReq GleanP
 		if err := r.sendRequest(req); err != nil {
 			r.logger.Error("Failed to forward ping to downstream",
 				zap.Error(err),
-				zap.String("downstream_url", r.cfg.ForwardURL))
+				zap.String("downstream_url", r.url))
 		}
 	}()
 	return nil
@@ -61,7 +74,7 @@ func (r *gleanPingForwarder) forwardRawPing(ctx context.Context, gleanReq GleanP
 
 // Create the full url with glean ping document paths (ns, type, version, id)
 func (r *gleanPingForwarder) makeURL(gleanReq GleanPingRequest) (*url.URL, error) {
-	baseURL, err := url.Parse(r.cfg.ForwardURL)
+	baseURL, err := url.Parse(r.url)
 	if err != nil {
 		return nil, err
 	}
@@ -82,8 +95,12 @@ func (r *gleanPingForwarder) buildRequest(ctx context.Context, gleanReq GleanPin
 		return nil, fmt.Errorf("failed to create forward request: %w", err)
 	}
 
-	// Set cloned headers
-	req.Header = gleanReq.Headers
+	// Set cloned headers, the same request headers may be forwarded to
+	// more than one downstream
+	req.Header = gleanReq.Headers.Clone()
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
 
 	// Add custom headers from config
 	for key, value := range r.cfg.ForwardHeaders {
@@ -107,7 +124,7 @@ func (r *gleanPingForwarder) sendRequest(req *http.Request) error {
 	}
 
 	r.logger.Debug("Successfully forwarded raw Glean ping",
-		zap.String("url", r.cfg.ForwardURL),
+		zap.String("url", r.url),
 		zap.Int("status", resp.StatusCode))
 
 	return nil
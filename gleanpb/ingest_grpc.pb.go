@@ -0,0 +1,138 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.0
+// - protoc             (unknown)
+// source: gleanpb/ingest.proto
+
+package gleanpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PingIngest_Submit_FullMethodName = "/glean.ingest.v1.PingIngest/Submit"
+)
+
+// PingIngestClient is the client API for PingIngest service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// PingIngest is the ingestion API of the Glean receiver for server-side
+// producers, such as Glean Server and backend services, that would rather not
+// build submission URLs. Pings go through the same checks and conversion as
+// pings posted to the HTTP submission endpoint.
+type PingIngestClient interface {
+	// Submit processes the pings of a request in order and streams one
+	// acknowledgment per ping as soon as it has been processed.
+	Submit(ctx context.Context, in *SubmitRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PingAck], error)
+}
+
+type pingIngestClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPingIngestClient(cc grpc.ClientConnInterface) PingIngestClient {
+	return &pingIngestClient{cc}
+}
+
+func (c *pingIngestClient) Submit(ctx context.Context, in *SubmitRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PingAck], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PingIngest_ServiceDesc.Streams[0], PingIngest_Submit_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubmitRequest, PingAck]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PingIngest_SubmitClient = grpc.ServerStreamingClient[PingAck]
+
+// PingIngestServer is the server API for PingIngest service.
+// All implementations must embed UnimplementedPingIngestServer
+// for forward compatibility.
+//
+// PingIngest is the ingestion API of the Glean receiver for server-side
+// producers, such as Glean Server and backend services, that would rather not
+// build submission URLs. Pings go through the same checks and conversion as
+// pings posted to the HTTP submission endpoint.
+type PingIngestServer interface {
+	// Submit processes the pings of a request in order and streams one
+	// acknowledgment per ping as soon as it has been processed.
+	Submit(*SubmitRequest, grpc.ServerStreamingServer[PingAck]) error
+	mustEmbedUnimplementedPingIngestServer()
+}
+
+// UnimplementedPingIngestServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPingIngestServer struct{}
+
+func (UnimplementedPingIngestServer) Submit(*SubmitRequest, grpc.ServerStreamingServer[PingAck]) error {
+	return status.Error(codes.Unimplemented, "method Submit not implemented")
+}
+func (UnimplementedPingIngestServer) mustEmbedUnimplementedPingIngestServer() {}
+func (UnimplementedPingIngestServer) testEmbeddedByValue()                    {}
+
+// UnsafePingIngestServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PingIngestServer will
+// result in compilation errors.
+type UnsafePingIngestServer interface {
+	mustEmbedUnimplementedPingIngestServer()
+}
+
+func RegisterPingIngestServer(s grpc.ServiceRegistrar, srv PingIngestServer) {
+	// If the following call panics, it indicates UnimplementedPingIngestServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PingIngest_ServiceDesc, srv)
+}
+
+func _PingIngest_Submit_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubmitRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PingIngestServer).Submit(m, &grpc.GenericServerStream[SubmitRequest, PingAck]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PingIngest_SubmitServer = grpc.ServerStreamingServer[PingAck]
+
+// PingIngest_ServiceDesc is the grpc.ServiceDesc for PingIngest service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PingIngest_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "glean.ingest.v1.PingIngest",
+	HandlerType: (*PingIngestServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Submit",
+			Handler:       _PingIngest_Submit_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "gleanpb/ingest.proto",
+}
@@ -0,0 +1,79 @@
+package gleanreceiver
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/mozilla/gleanotelreceiver/gleanpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// pingIngestServer implements the gRPC ingestion API on top of the receiver
+type pingIngestServer struct {
+	gleanpb.UnimplementedPingIngestServer
+	receiver *gleanReceiver
+}
+
+// Submit runs each ping of the request through the receiver and acknowledges
+// it as soon as it has been processed
+func (s *pingIngestServer) Submit(req *gleanpb.SubmitRequest, stream grpc.ServerStreamingServer[gleanpb.PingAck]) error {
+	ctx := stream.Context()
+	for i, envelope := range req.GetPings() {
+		if err := ctx.Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+
+		err := s.ingestEnvelope(ctx, envelope)
+		if err := stream.Send(newPingAck(i, envelope.GetDocumentId(), err)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ingestEnvelope runs a single ping envelope through the receiver
+func (s *pingIngestServer) ingestEnvelope(ctx context.Context, envelope *gleanpb.PingEnvelope) error {
+	gleanRequest := GleanPingRequest{
+		Namespace:       envelope.GetNamespace(),
+		DocumentType:    envelope.GetDocumentType(),
+		DocumentVersion: envelope.GetDocumentVersion(),
+		DocumentID:      envelope.GetDocumentId(),
+		Headers:         http.Header{},
+		SubmissionTime:  time.Now(),
+	}
+	// The HTTP path cannot match without these, so refuse them the same way
+	if gleanRequest.Namespace == "" || gleanRequest.DocumentType == "" ||
+		gleanRequest.DocumentVersion == "" || gleanRequest.DocumentID == "" {
+		return newPingError(http.StatusBadRequest,
+			"namespace, document_type, document_version and document_id are required")
+	}
+	for name, value := range envelope.GetHeaders() {
+		gleanRequest.Headers.Set(name, value)
+	}
+
+	return s.receiver.ingestPing(ctx, gleanRequest, func() ([]byte, error) {
+		return envelope.GetPayload(), nil
+	})
+}
+
+// newPingAck builds the acknowledgment for the result of ingestPing
+func newPingAck(index int, documentID string, err error) *gleanpb.PingAck {
+	ack := &gleanpb.PingAck{
+		Index:      int32(index),
+		DocumentId: documentID,
+		HttpStatus: int32(pingStatus(err)),
+	}
+	switch {
+	case err == nil:
+		ack.Outcome = gleanpb.Outcome_OUTCOME_ACCEPTED
+	case ack.HttpStatus >= http.StatusInternalServerError:
+		ack.Outcome = gleanpb.Outcome_OUTCOME_RETRYABLE
+		ack.Message = err.Error()
+	default:
+		ack.Outcome = gleanpb.Outcome_OUTCOME_REJECTED
+		ack.Message = err.Error()
+	}
+	return ack
+}
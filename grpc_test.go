@@ -0,0 +1,213 @@
+package gleanreceiver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mozilla/gleanotelreceiver/gleanpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config/configgrpc"
+	"go.opentelemetry.io/collector/config/configoptional"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestNewPingAck(t *testing.T) {
+	tests := []struct {
+		name            string
+		err             error
+		expectedOutcome gleanpb.Outcome
+		expectedStatus  int32
+	}{
+		{
+			name:            "accepted",
+			expectedOutcome: gleanpb.Outcome_OUTCOME_ACCEPTED,
+			expectedStatus:  http.StatusOK,
+		},
+		{
+			name:            "refused",
+			err:             newPingError(http.StatusBadRequest, "Invalid JSON format"),
+			expectedOutcome: gleanpb.Outcome_OUTCOME_REJECTED,
+			expectedStatus:  http.StatusBadRequest,
+		},
+		{
+			name:            "pipeline failure",
+			err:             newPingError(http.StatusInternalServerError, "Failed to process metrics"),
+			expectedOutcome: gleanpb.Outcome_OUTCOME_RETRYABLE,
+			expectedStatus:  http.StatusInternalServerError,
+		},
+		{
+			name:            "unexpected error",
+			err:             errors.New("boom"),
+			expectedOutcome: gleanpb.Outcome_OUTCOME_RETRYABLE,
+			expectedStatus:  http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ack := newPingAck(2, "doc-1", tt.err)
+			assert.Equal(t, int32(2), ack.GetIndex())
+			assert.Equal(t, "doc-1", ack.GetDocumentId())
+			assert.Equal(t, tt.expectedOutcome, ack.GetOutcome())
+			assert.Equal(t, tt.expectedStatus, ack.GetHttpStatus())
+			if tt.err != nil {
+				assert.Equal(t, tt.err.Error(), ack.GetMessage())
+			}
+		})
+	}
+}
+
+func TestReceiverGRPC(t *testing.T) {
+	grpcCfg := configgrpc.NewDefaultServerConfig()
+	grpcCfg.NetAddr.Endpoint = "localhost:19911"
+	cfg := &Config{
+		Path:           "/test",
+		RequestHeaders: []string{"X-Debug-ID"},
+		GRPC:           configoptional.Some(grpcCfg),
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19910"
+
+	metricsSink := new(consumertest.MetricsSink)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		metricsSink,
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	conn, err := grpc.NewClient("localhost:19911", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	stream, err := gleanpb.NewPingIngestClient(conn).Submit(ctx, &gleanpb.SubmitRequest{
+		Pings: []*gleanpb.PingEnvelope{
+			{
+				Namespace:       "test-app",
+				DocumentType:    "metrics",
+				DocumentVersion: "1",
+				DocumentId:      "doc-1",
+				Headers:         map[string]string{"x-debug-id": "qa"},
+				Payload:         []byte(envelopePingBody),
+			},
+			{
+				Namespace:       "test-app",
+				DocumentType:    "metrics",
+				DocumentVersion: "1",
+				DocumentId:      "doc-2",
+				Payload:         []byte("not json"),
+			},
+			{
+				Namespace: "test-app",
+				Payload:   []byte(envelopePingBody),
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	var acks []*gleanpb.PingAck
+	for {
+		ack, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		acks = append(acks, ack)
+	}
+
+	require.Len(t, acks, 3)
+	assert.Equal(t, gleanpb.Outcome_OUTCOME_ACCEPTED, acks[0].GetOutcome())
+	assert.Equal(t, "doc-1", acks[0].GetDocumentId())
+	assert.Equal(t, gleanpb.Outcome_OUTCOME_REJECTED, acks[1].GetOutcome())
+	assert.Equal(t, int32(http.StatusBadRequest), acks[1].GetHttpStatus())
+	assert.Equal(t, "Invalid JSON format", acks[1].GetMessage())
+	assert.Equal(t, gleanpb.Outcome_OUTCOME_REJECTED, acks[2].GetOutcome())
+	assert.Equal(t, int32(2), acks[2].GetIndex())
+
+	assert.Eventually(t, func() bool {
+		return len(metricsSink.AllMetrics()) == 1
+	}, time.Second, 10*time.Millisecond)
+	attrs := metricsSink.AllMetrics()[0].ResourceMetrics().At(0).Resource().Attributes()
+	debugID, exists := attrs.Get("http.request.header.x-debug-id")
+	assert.True(t, exists)
+	assert.Equal(t, "qa", debugID.Str())
+}
+
+func TestReceiverGRPCShutdownDeadline(t *testing.T) {
+	grpcCfg := configgrpc.NewDefaultServerConfig()
+	grpcCfg.NetAddr.Endpoint = "localhost:19926"
+	cfg := &Config{
+		Path: "/test",
+		GRPC: configoptional.Some(grpcCfg),
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19925"
+
+	// The consumer only returns once the call is cancelled
+	consuming := make(chan struct{}, 1)
+	metricsConsumer, err := consumer.NewMetrics(func(ctx context.Context, _ pmetric.Metrics) error {
+		consuming <- struct{}{}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	require.NoError(t, err)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		metricsConsumer,
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+	require.NoError(t, receiver.Start(context.Background(), componenttest.NewNopHost()))
+
+	conn, err := grpc.NewClient("localhost:19926", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	stream, err := gleanpb.NewPingIngestClient(conn).Submit(context.Background(), &gleanpb.SubmitRequest{
+		Pings: []*gleanpb.PingEnvelope{{
+			Namespace:       "test-app",
+			DocumentType:    "metrics",
+			DocumentVersion: "1",
+			DocumentId:      "doc-1",
+			Payload:         []byte(envelopePingBody),
+		}},
+	})
+	require.NoError(t, err)
+	go func() {
+		for {
+			if _, err := stream.Recv(); err != nil {
+				return
+			}
+		}
+	}()
+	<-consuming
+
+	// Shutdown does not wait for the call past its deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	err = receiver.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(started), 5*time.Second)
+}
@@ -0,0 +1,147 @@
+package gleanreceiver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.uber.org/zap"
+)
+
+// Initial offsets for partitions without a committed offset
+const (
+	kafkaOffsetLatest   = "latest"
+	kafkaOffsetEarliest = "earliest"
+)
+
+// kafkaMaxPollRecords bounds the records processed between offset commits
+const kafkaMaxPollRecords = 100
+
+// refusedKafkaRetriesExhausted is the refused pings reason for Kafka pings
+// skipped after max_retries
+const refusedKafkaRetriesExhausted = "kafka_retries_exhausted"
+
+// newKafkaClient creates a consumer group client that leaves committing
+// offsets to the receiver
+func newKafkaClient(cfg KafkaConfig) (*kgo.Client, error) {
+	offset := kgo.NewOffset().AtEnd()
+	if cfg.InitialOffset == kafkaOffsetEarliest {
+		offset = kgo.NewOffset().AtStart()
+	}
+	return kgo.NewClient(
+		kgo.SeedBrokers(cfg.Brokers...),
+		kgo.ConsumerGroup(cfg.GroupID),
+		kgo.ConsumeTopics(cfg.Topics...),
+		kgo.ConsumeResetOffset(offset),
+		kgo.DisableAutoCommit(),
+		// Hold rebalances until the polled records are processed and
+		// committed, so another member does not process them again
+		kgo.BlockRebalanceOnPoll(),
+	)
+}
+
+// consumeKafka ingests ping envelopes until the receiver shuts down. Offsets
+// are committed once the pings they cover have been accepted by the
+// pipeline or permanently refused.
+func (r *gleanReceiver) consumeKafka(client *kgo.Client) {
+	defer r.background.Done()
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-r.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		fetches := client.PollRecords(ctx, kafkaMaxPollRecords)
+		if fetches.IsClientClosed() || ctx.Err() != nil {
+			// Leaving the group on close waits for rebalances to be allowed
+			client.AllowRebalance()
+			return
+		}
+		fetches.EachError(func(topic string, partition int32, err error) {
+			r.logger.Warn("Failed to fetch from Kafka",
+				zap.String("topic", topic), zap.Int32("partition", partition), zap.Error(err))
+		})
+
+		var processed []*kgo.Record
+		for records := fetches.RecordIter(); !records.Done(); {
+			record := records.Next()
+			if !r.ingestKafkaRecord(ctx, record) {
+				break
+			}
+			processed = append(processed, record)
+		}
+
+		if len(processed) > 0 {
+			if err := client.CommitRecords(context.Background(), processed...); err != nil {
+				r.logger.Warn("Failed to commit Kafka offsets", zap.Error(err))
+			}
+		}
+		client.AllowRebalance()
+	}
+}
+
+// ingestKafkaRecord runs a single record through the receiver, retrying
+// while the pipeline fails up to max_retries times. Retries only redo the
+// steps that failed: the ping is forwarded once and signals the pipeline
+// accepted are not sent again. It returns false if the receiver shut down
+// before the record was processed.
+func (r *gleanReceiver) ingestKafkaRecord(ctx context.Context, record *kgo.Record) bool {
+	gleanRequest, body, err := parsePingEnvelope(record.Value, r.cfg.maxDecompressedBytes())
+	if errors.Is(err, errDecompressedTooLarge) {
+		r.telemetry.recordLimitedPing(ctx, limitDecompressedBytes, limitActionReject)
+	}
+	if err != nil {
+		r.logger.Warn("Skipping malformed Kafka record",
+			zap.String("topic", record.Topic),
+			zap.Int32("partition", record.Partition),
+			zap.Int64("offset", record.Offset),
+			zap.Error(err))
+		return true
+	}
+	if gleanRequest.SubmissionTime.IsZero() {
+		gleanRequest.SubmissionTime = record.Timestamp
+	}
+	gleanRequest.Delivery = newPingDelivery()
+
+	for retries := 0; ; retries++ {
+		err := r.ingestPing(ctx, gleanRequest, func() ([]byte, error) { return body, nil })
+		if pingStatus(err) < http.StatusInternalServerError {
+			if err != nil {
+				r.logger.Debug("Kafka ping refused", zap.String("document_id", gleanRequest.DocumentID), zap.Error(err))
+			}
+			return true
+		}
+
+		// Skip the record rather than stall the partition on it
+		if r.cfg.Kafka.MaxRetries > 0 && retries >= r.cfg.Kafka.MaxRetries {
+			r.telemetry.recordRefusedPing(ctx, refusedKafkaRetriesExhausted)
+			r.logger.Error("Skipping Kafka ping after exhausting retries",
+				zap.String("topic", record.Topic),
+				zap.Int32("partition", record.Partition),
+				zap.Int64("offset", record.Offset),
+				zap.String("document_id", gleanRequest.DocumentID),
+				zap.Int("retries", retries),
+				zap.Error(err))
+			return true
+		}
+
+		r.logger.Warn("Failed to ingest Kafka ping, retrying",
+			zap.String("document_id", gleanRequest.DocumentID),
+			zap.Duration("backoff", r.cfg.Kafka.RetryBackoff),
+			zap.Error(err))
+		select {
+		case <-time.After(r.cfg.Kafka.RetryBackoff):
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package gleanreceiver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+)
+
+// flakyMetricsSink fails the first failures calls to ConsumeMetrics
+type flakyMetricsSink struct {
+	consumertest.MetricsSink
+	failures atomic.Int32
+}
+
+func (s *flakyMetricsSink) ConsumeMetrics(ctx context.Context, md pmetric.Metrics) error {
+	if s.failures.Add(-1) >= 0 {
+		return errors.New("pipeline unavailable")
+	}
+	return s.MetricsSink.ConsumeMetrics(ctx, md)
+}
+
+// flakyLogsSink fails the first failures calls to ConsumeLogs
+type flakyLogsSink struct {
+	consumertest.LogsSink
+	failures atomic.Int32
+}
+
+func (s *flakyLogsSink) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
+	if s.failures.Add(-1) >= 0 {
+		return errors.New("pipeline unavailable")
+	}
+	return s.LogsSink.ConsumeLogs(ctx, ld)
+}
+
+func TestReceiverKafka(t *testing.T) {
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(1, "glean-pings"))
+	require.NoError(t, err)
+	defer cluster.Close()
+
+	producer, err := kgo.NewClient(kgo.SeedBrokers(cluster.ListenAddrs()...))
+	require.NoError(t, err)
+	defer producer.Close()
+
+	ctx := context.Background()
+	for _, value := range []string{
+		`{"uri":"/submit/test-app/metrics/1/doc-1","headers":{"X-Debug-ID":"qa"},"payload":` + envelopePingBody + `}`,
+		`not json`,
+		`{"uri":"/submit/test-app/metrics/1/doc-2","payload":` + envelopePingBody + `}`,
+	} {
+		require.NoError(t, producer.ProduceSync(ctx, &kgo.Record{Topic: "glean-pings", Value: []byte(value)}).FirstErr())
+	}
+
+	cfg := &Config{
+		Path:           "/test",
+		RequestHeaders: []string{"X-Debug-ID"},
+		Kafka: KafkaConfig{
+			Brokers:       cluster.ListenAddrs(),
+			Topics:        []string{"glean-pings"},
+			GroupID:       "glean-receiver",
+			InitialOffset: kafkaOffsetEarliest,
+			RetryBackoff:  10 * time.Millisecond,
+		},
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19909"
+
+	metricsSink := new(flakyMetricsSink)
+	metricsSink.failures.Store(2)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		metricsSink,
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	// The first ping is retried until the pipeline accepts it
+	assert.Eventually(t, func() bool {
+		return len(metricsSink.AllMetrics()) == 2
+	}, 10*time.Second, 10*time.Millisecond)
+
+	md := client.FromContext(metricsSink.Contexts()[0]).Metadata
+	assert.Equal(t, []string{"doc-1"}, md.Get("document_id"))
+	attrs := metricsSink.AllMetrics()[0].ResourceMetrics().At(0).Resource().Attributes()
+	_, exists := attrs.Get("document.id")
+	assert.False(t, exists)
+	debugID, exists := attrs.Get("http.request.header.x-debug-id")
+	assert.True(t, exists)
+	assert.Equal(t, "qa", debugID.Str())
+
+	// Offsets are committed past every processed record, including the
+	// malformed one
+	admin := kadm.NewClient(producer)
+	assert.Eventually(t, func() bool {
+		offsets, err := admin.FetchOffsets(ctx, "glean-receiver")
+		if err != nil {
+			return false
+		}
+		offset, ok := offsets.Lookup("glean-pings", 0)
+		return ok && offset.At == 3
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+func TestReceiverKafkaMaxRetries(t *testing.T) {
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(1, "glean-pings"))
+	require.NoError(t, err)
+	defer cluster.Close()
+
+	producer, err := kgo.NewClient(kgo.SeedBrokers(cluster.ListenAddrs()...))
+	require.NoError(t, err)
+	defer producer.Close()
+
+	ctx := context.Background()
+	for _, documentID := range []string{"doc-1", "doc-2"} {
+		value := `{"uri":"/submit/test-app/metrics/1/` + documentID + `","payload":` + envelopePingBody + `}`
+		require.NoError(t, producer.ProduceSync(ctx, &kgo.Record{Topic: "glean-pings", Value: []byte(value)}).FirstErr())
+	}
+
+	cfg := &Config{
+		Path: "/test",
+		Kafka: KafkaConfig{
+			Brokers:       cluster.ListenAddrs(),
+			Topics:        []string{"glean-pings"},
+			GroupID:       "glean-receiver",
+			InitialOffset: kafkaOffsetEarliest,
+			RetryBackoff:  10 * time.Millisecond,
+			MaxRetries:    2,
+		},
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19921"
+
+	// The first ping fails its first attempt and both retries
+	metricsSink := new(flakyMetricsSink)
+	metricsSink.failures.Store(3)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		metricsSink,
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	// The first ping is skipped and the partition moves on
+	assert.Eventually(t, func() bool {
+		return len(metricsSink.AllMetrics()) == 1
+	}, 10*time.Second, 10*time.Millisecond)
+	md := client.FromContext(metricsSink.Contexts()[0]).Metadata
+	assert.Equal(t, []string{"doc-2"}, md.Get("document_id"))
+
+	admin := kadm.NewClient(producer)
+	assert.Eventually(t, func() bool {
+		offsets, err := admin.FetchOffsets(ctx, "glean-receiver")
+		if err != nil {
+			return false
+		}
+		offset, ok := offsets.Lookup("glean-pings", 0)
+		return ok && offset.At == 2
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+func TestReceiverKafkaRetryOnlyFailedSteps(t *testing.T) {
+	var forwarded atomic.Int32
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer downstream.Close()
+
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(1, "glean-pings"))
+	require.NoError(t, err)
+	defer cluster.Close()
+
+	producer, err := kgo.NewClient(kgo.SeedBrokers(cluster.ListenAddrs()...))
+	require.NoError(t, err)
+	defer producer.Close()
+
+	ctx := context.Background()
+	value := `{"uri":"/submit/test-app/events/1/doc-1","payload":{"ping_info":{"seq":1,"start_time":"2024-01-28T10:00:00Z","end_time":"2024-01-28T10:01:00Z"},"client_info":{"client_id":"test-client"},"metrics":{"counter":{"test_counter":5}},"events":[{"timestamp":0,"category":"ui","name":"click"}]}}`
+	require.NoError(t, producer.ProduceSync(ctx, &kgo.Record{Topic: "glean-pings", Value: []byte(value)}).FirstErr())
+
+	cfg := &Config{
+		Path:       "/test",
+		ForwardURL: downstream.URL,
+		Kafka: KafkaConfig{
+			Brokers:       cluster.ListenAddrs(),
+			Topics:        []string{"glean-pings"},
+			GroupID:       "glean-receiver",
+			InitialOffset: kafkaOffsetEarliest,
+			RetryBackoff:  10 * time.Millisecond,
+		},
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19931"
+
+	// Metrics are accepted on the first attempt, event logs on the fourth
+	metricsSink := new(consumertest.MetricsSink)
+	logsSink := new(flakyLogsSink)
+	logsSink.failures.Store(3)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		metricsSink,
+		logsSink,
+		nil,
+	)
+	require.NoError(t, err)
+
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	assert.Eventually(t, func() bool {
+		return len(logsSink.AllLogs()) == 1
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// Retries neither forwarded the ping nor sent its metrics again
+	assert.Equal(t, int32(1), forwarded.Load())
+	assert.Len(t, metricsSink.AllMetrics(), 1)
+}
@@ -0,0 +1,287 @@
+package gleanreceiver
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Payload limits, used as the limit attribute of the limited pings counter
+const (
+	limitBodyBytes         = "body_bytes"
+	limitDecompressedBytes = "decompressed_bytes"
+	limitEvents            = "events"
+	limitMetrics           = "metrics"
+	limitLabels            = "labels"
+	limitStringLength      = "string_length"
+)
+
+// Actions taken on pings over a limit
+const (
+	limitActionTruncate = "truncate"
+	limitActionReject   = "reject"
+)
+
+// errDecompressedTooLarge is returned for gzip bodies over max_decompressed_bytes
+var errDecompressedTooLarge = newPingError(http.StatusRequestEntityTooLarge, "Decompressed ping exceeds the maximum size")
+
+// maxDecompressedBytes returns the largest ping body accepted once
+// decompressed
+func (cfg *Config) maxDecompressedBytes() int64 {
+	if cfg.Limits.MaxDecompressedBytes > 0 {
+		return cfg.Limits.MaxDecompressedBytes
+	}
+	return cfg.maxRequestBodySize()
+}
+
+// isIdentityEncoding reports whether a Content-Encoding leaves the body as is
+func isIdentityEncoding(encoding string) bool {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return true
+	default:
+		return false
+	}
+}
+
+// readRequestBody reads the body of an HTTP ping, decompressing gzip bodies
+// up to maxDecompressed bytes as Glean SDKs send them. The decompressed
+// bytes are reserved against admission control as they are read.
+func readRequestBody(req *http.Request, maxDecompressed int64, admitted *admittedRequest) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(req.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+		return io.ReadAll(admitted.reader(req.Body))
+	case "gzip", "x-gzip":
+		reader, err := gzip.NewReader(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		body, err := io.ReadAll(admitted.reader(io.LimitReader(reader, maxDecompressed+1)))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(body)) > maxDecompressed {
+			return nil, errDecompressedTooLarge
+		}
+		return body, nil
+	default:
+		return nil, newPingError(http.StatusUnsupportedMediaType, "Unsupported Content-Encoding")
+	}
+}
+
+// pingLimits bounds the events, metrics, labels and strings of a ping
+type pingLimits struct {
+	maxEvents       int
+	maxMetrics      int
+	maxLabels       int
+	maxStringLength int
+	action          string
+}
+
+// newPingLimits returns nil when no count or length limit is configured
+func newPingLimits(cfg LimitsConfig) *pingLimits {
+	if cfg.MaxEvents <= 0 && cfg.MaxMetrics <= 0 && cfg.MaxLabels <= 0 && cfg.MaxStringLength <= 0 {
+		return nil
+	}
+	action := cfg.Action
+	if action == "" {
+		action = limitActionTruncate
+	}
+	return &pingLimits{
+		maxEvents:       cfg.MaxEvents,
+		maxMetrics:      cfg.MaxMetrics,
+		maxLabels:       cfg.MaxLabels,
+		maxStringLength: cfg.MaxStringLength,
+		action:          action,
+	}
+}
+
+// apply truncates the ping in place to the limits and returns the limits it
+// exceeded. Metrics and labels are kept in name order.
+func (l *pingLimits) apply(ping *GleanPing) []string {
+	var exceeded []string
+
+	if l.maxEvents > 0 && len(ping.Events) > l.maxEvents {
+		ping.Events = ping.Events[:l.maxEvents]
+		exceeded = append(exceeded, limitEvents)
+	}
+
+	if l.maxMetrics > 0 && l.truncateMetrics(ping.Metrics) {
+		exceeded = append(exceeded, limitMetrics)
+	}
+
+	if l.maxLabels > 0 && l.truncateLabels(ping.Metrics) {
+		exceeded = append(exceeded, limitLabels)
+	}
+
+	if l.maxStringLength > 0 && l.truncateStrings(ping) {
+		exceeded = append(exceeded, limitStringLength)
+	}
+
+	return exceeded
+}
+
+// truncateMetrics keeps the first maxMetrics metrics ordered by type and name
+func (l *pingLimits) truncateMetrics(metrics map[string]any) bool {
+	type metricKey struct{ metricType, name string }
+	var keys []metricKey
+	for metricType, value := range metrics {
+		metricsOfType, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+		for name := range metricsOfType {
+			keys = append(keys, metricKey{metricType, name})
+		}
+	}
+	if len(keys) <= l.maxMetrics {
+		return false
+	}
+
+	slices.SortFunc(keys, func(a, b metricKey) int {
+		if c := strings.Compare(a.metricType, b.metricType); c != 0 {
+			return c
+		}
+		return strings.Compare(a.name, b.name)
+	})
+	for _, key := range keys[l.maxMetrics:] {
+		metricsOfType := metrics[key.metricType].(map[string]any)
+		delete(metricsOfType, key.name)
+		if len(metricsOfType) == 0 {
+			delete(metrics, key.metricType)
+		}
+	}
+	return true
+}
+
+// truncateLabels keeps the first maxLabels labels of every labeled metric
+func (l *pingLimits) truncateLabels(metrics map[string]any) bool {
+	truncated := false
+	for metricType, value := range metrics {
+		metricsOfType, ok := value.(map[string]any)
+		if !ok || !strings.HasPrefix(metricType, "labeled_") {
+			continue
+		}
+		for _, metricValue := range metricsOfType {
+			labels, ok := metricValue.(map[string]any)
+			if !ok || len(labels) <= l.maxLabels {
+				continue
+			}
+			names := make([]string, 0, len(labels))
+			for label := range labels {
+				names = append(names, label)
+			}
+			slices.Sort(names)
+			for _, label := range names[l.maxLabels:] {
+				delete(labels, label)
+			}
+			truncated = true
+		}
+	}
+	return truncated
+}
+
+// truncateStrings shortens the names and string values of metrics, the
+// labels of labeled metrics and the category, name and extras of events to
+// maxStringLength characters
+func (l *pingLimits) truncateStrings(ping *GleanPing) bool {
+	truncated := false
+	for metricType, value := range ping.Metrics {
+		metricsOfType, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+		for name, metricValue := range metricsOfType {
+			if strings.HasPrefix(metricType, "labeled_") {
+				if labels, ok := metricValue.(map[string]any); ok {
+					truncated = truncateKeys(labels, l.maxStringLength) || truncated
+				}
+			}
+			metricsOfType[name] = l.truncateValue(metricValue, &truncated)
+		}
+		truncated = truncateKeys(metricsOfType, l.maxStringLength) || truncated
+	}
+
+	for i := range ping.Events {
+		event := &ping.Events[i]
+		for _, field := range []*string{&event.Category, &event.Name} {
+			if short, ok := truncateString(*field, l.maxStringLength); ok {
+				*field = short
+				truncated = true
+			}
+		}
+		truncated = truncateKeys(event.Extra, l.maxStringLength) || truncated
+		for key, value := range event.Extra {
+			if short, ok := truncateString(value, l.maxStringLength); ok {
+				event.Extra[key] = short
+				truncated = true
+			}
+		}
+	}
+	return truncated
+}
+
+// truncateValue shortens the strings nested in a metric value
+func (l *pingLimits) truncateValue(value any, truncated *bool) any {
+	switch v := value.(type) {
+	case string:
+		if short, ok := truncateString(v, l.maxStringLength); ok {
+			*truncated = true
+			return short
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = l.truncateValue(item, truncated)
+		}
+	case map[string]any:
+		for key, item := range v {
+			v[key] = l.truncateValue(item, truncated)
+		}
+	}
+	return value
+}
+
+// truncateKeys shortens the keys of a map. A truncated key never replaces
+// a key already in the map, and of keys truncated to the same prefix the
+// first in name order keeps its value.
+func truncateKeys[V any](values map[string]V, length int) bool {
+	var long []string
+	for key := range values {
+		if _, ok := truncateString(key, length); ok {
+			long = append(long, key)
+		}
+	}
+	if len(long) == 0 {
+		return false
+	}
+
+	slices.Sort(long)
+	renamed := make(map[string]V, len(long))
+	for _, key := range long {
+		renamed[key] = values[key]
+		delete(values, key)
+	}
+	for _, key := range long {
+		short, _ := truncateString(key, length)
+		if _, exists := values[short]; !exists {
+			values[short] = renamed[key]
+		}
+	}
+	return true
+}
+
+// truncateString shortens value to length characters, reporting whether it
+// was longer
+func truncateString(value string, length int) (string, bool) {
+	if len(value) <= length {
+		return value, false
+	}
+	runes := []rune(value)
+	if len(runes) <= length {
+		return value, false
+	}
+	return string(runes[:length]), true
+}
@@ -0,0 +1,273 @@
+package gleanreceiver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+)
+
+func TestReadRequestBody(t *testing.T) {
+	body := []byte(envelopePingBody)
+
+	tests := []struct {
+		name       string
+		encoding   string
+		body       []byte
+		maxBytes   int64
+		wantStatus int
+		wantErr    bool
+	}{
+		{
+			name:     "identity",
+			body:     body,
+			maxBytes: 10,
+		},
+		{
+			name:     "gzip",
+			encoding: "gzip",
+			body:     gzipBytes(t, body),
+			maxBytes: int64(len(body)),
+		},
+		{
+			name:       "gzip over the decompressed limit",
+			encoding:   "gzip",
+			body:       gzipBytes(t, body),
+			maxBytes:   int64(len(body)) - 1,
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:     "invalid gzip",
+			encoding: "gzip",
+			body:     body,
+			maxBytes: int64(len(body)),
+			wantErr:  true,
+		},
+		{
+			name:       "unsupported encoding",
+			encoding:   "br",
+			body:       body,
+			maxBytes:   int64(len(body)),
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+
+			got, err := readRequestBody(req, tt.maxBytes, nil)
+			switch {
+			case tt.wantStatus != 0:
+				assert.Equal(t, tt.wantStatus, pingStatus(err))
+			case tt.wantErr:
+				assert.Error(t, err)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, body, got)
+			}
+		})
+	}
+}
+
+func TestNewPingLimits(t *testing.T) {
+	assert.Nil(t, newPingLimits(LimitsConfig{MaxDecompressedBytes: 1024}))
+
+	limits := newPingLimits(LimitsConfig{MaxEvents: 10})
+	require.NotNil(t, limits)
+	assert.Equal(t, limitActionTruncate, limits.action)
+}
+
+func TestPingLimitsApply(t *testing.T) {
+	limits := newPingLimits(LimitsConfig{
+		MaxEvents:       2,
+		MaxMetrics:      3,
+		MaxLabels:       2,
+		MaxStringLength: 4,
+	})
+
+	ping := &GleanPing{
+		Metrics: map[string]any{
+			"counter": map[string]any{"a.ct": float64(1)},
+			"labeled_counter": map[string]any{
+				"a.by": map[string]any{"crash": float64(1), "hang": float64(2), "oom": float64(3)},
+			},
+			"string":      map[string]any{"a.nm": "ünïcode", "b.nm": "ok"},
+			"string_list": map[string]any{"z.ls": []any{"first", "second"}},
+		},
+		Events: []Event{
+			{Category: "ui", Name: "click", Extra: map[string]string{"target": "button"}},
+			{Category: "ui", Name: "click"},
+			{Category: "ui", Name: "click"},
+		},
+	}
+
+	exceeded := limits.apply(ping)
+	assert.Equal(t, []string{limitEvents, limitMetrics, limitLabels, limitStringLength}, exceeded)
+
+	assert.Len(t, ping.Events, 2)
+	assert.Equal(t, "clic", ping.Events[0].Name)
+	assert.Equal(t, map[string]string{"targ": "butt"}, ping.Events[0].Extra)
+
+	// Metrics are kept in type and name order
+	assert.Equal(t, map[string]any{
+		"counter": map[string]any{"a.ct": float64(1)},
+		"labeled_counter": map[string]any{
+			"a.by": map[string]any{"cras": float64(1), "hang": float64(2)},
+		},
+		"string": map[string]any{"a.nm": "ünïc"},
+	}, ping.Metrics)
+
+	// Pings within the limits are left unchanged
+	ping = &GleanPing{
+		Metrics: map[string]any{"counter": map[string]any{"a.ct": float64(1)}},
+		Events:  []Event{{Category: "ui", Name: "tap"}},
+	}
+	assert.Empty(t, limits.apply(ping))
+}
+
+func TestTruncateKeys(t *testing.T) {
+	// Truncated keys never replace existing ones, and of keys truncated to
+	// the same prefix the first in name order is kept
+	values := map[string]any{
+		"crash":       float64(1),
+		"crash_gpu":   float64(2),
+		"hang_main":   float64(3),
+		"hang_render": float64(4),
+		"oom":         float64(5),
+	}
+	assert.True(t, truncateKeys(values, 5))
+	assert.Equal(t, map[string]any{
+		"crash": float64(1),
+		"hang_": float64(3),
+		"oom":   float64(5),
+	}, values)
+
+	assert.False(t, truncateKeys(values, 5))
+}
+
+func TestReceiverLimits(t *testing.T) {
+	newPing := func(events int) []byte {
+		ping := GleanPing{
+			ClientInfo: ClientInfo{ClientID: "test-client"},
+			PingInfo:   PingInfo{StartTime: time.Now(), EndTime: time.Now(), PingType: "events"},
+		}
+		for range events {
+			ping.Events = append(ping.Events, Event{Category: "ui", Name: "click"})
+		}
+		body, err := json.Marshal(ping)
+		require.NoError(t, err)
+		return body
+	}
+
+	tests := []struct {
+		name       string
+		limits     LimitsConfig
+		body       []byte
+		encoding   string
+		wantStatus int
+		wantEvents int
+	}{
+		{
+			name:       "gzip",
+			body:       gzipBytes(t, newPing(3)),
+			encoding:   "gzip",
+			wantStatus: http.StatusOK,
+			wantEvents: 3,
+		},
+		{
+			name:       "gzip over the decompressed limit",
+			limits:     LimitsConfig{MaxDecompressedBytes: 64},
+			body:       gzipBytes(t, append(newPing(3), bytes.Repeat([]byte(" "), 4096)...)),
+			encoding:   "gzip",
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "truncated events",
+			limits:     LimitsConfig{MaxEvents: 2, Action: limitActionTruncate},
+			body:       newPing(3),
+			wantStatus: http.StatusOK,
+			wantEvents: 2,
+		},
+		{
+			name:       "rejected events",
+			limits:     LimitsConfig{MaxEvents: 2, Action: limitActionReject},
+			body:       newPing(3),
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Path:   "/test",
+				Limits: tt.limits,
+			}
+			cfg.ServerConfig.NetAddr.Endpoint = "localhost:19920"
+
+			logsSink := new(consumertest.LogsSink)
+
+			receiver, err := newGleanReceiver(
+				cfg,
+				receivertest.NewNopSettings(component.MustNewType("glean")),
+				nil,
+				logsSink,
+				nil,
+			)
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			err = receiver.Start(ctx, componenttest.NewNopHost())
+			require.NoError(t, err)
+			defer receiver.Shutdown(ctx)
+
+			req, err := http.NewRequest(http.MethodPost, "http://localhost:19920/test/test-app/events/1/doc-1", bytes.NewReader(tt.body))
+			require.NoError(t, err)
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+			// Do not reuse a connection to the receiver of the previous case
+			req.Close = true
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+
+			if tt.wantEvents == 0 {
+				assert.Empty(t, logsSink.AllLogs())
+				return
+			}
+			require.Len(t, logsSink.AllLogs(), 1)
+			assert.Equal(t, tt.wantEvents, logsSink.AllLogs()[0].LogRecordCount())
+		})
+	}
+}
+
+func TestTruncateString(t *testing.T) {
+	short, ok := truncateString("hello", 10)
+	assert.False(t, ok)
+	assert.Equal(t, "hello", short)
+
+	short, ok = truncateString(strings.Repeat("é", 6), 5)
+	assert.True(t, ok)
+	assert.Equal(t, strings.Repeat("é", 5), short)
+
+	// Multi-byte strings are measured in characters
+	short, ok = truncateString("ééé", 3)
+	assert.False(t, ok)
+	assert.Equal(t, "ééé", short)
+}
@@ -0,0 +1,83 @@
+package gleanreceiver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+
+	"go.opentelemetry.io/collector/config/confignet"
+)
+
+// listenAddrs returns every address the HTTP server listens on, the main
+// endpoint first
+func (cfg *Config) listenAddrs() []confignet.AddrConfig {
+	return append([]confignet.AddrConfig{cfg.NetAddr}, cfg.AdditionalEndpoints...)
+}
+
+// validateListenAddr checks an HTTP listen address
+func validateListenAddr(addr confignet.AddrConfig) error {
+	if addr.Endpoint == "" {
+		return errors.New("endpoint must not be empty")
+	}
+	switch addr.Transport {
+	case "", confignet.TransportTypeTCP, confignet.TransportTypeTCP4, confignet.TransportTypeTCP6, confignet.TransportTypeUnix:
+		return nil
+	default:
+		return fmt.Errorf("unsupported transport %q for %s", addr.Transport, addr.Endpoint)
+	}
+}
+
+// listen opens a listener for addr. Addresses without a transport use TCP.
+// A Unix socket left behind by a previous run is replaced.
+func listen(ctx context.Context, addr confignet.AddrConfig) (net.Listener, error) {
+	if addr.Transport == "" {
+		addr.Transport = confignet.TransportTypeTCP
+	}
+	if addr.Transport == confignet.TransportTypeUnix {
+		if err := removeStaleSocket(addr.Endpoint); err != nil {
+			return nil, err
+		}
+	}
+	return addr.Listen(ctx)
+}
+
+// removeStaleSocket removes the Unix socket at path if nothing accepts
+// connections on it. Other files and sockets still in use are left alone
+// and reported.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&fs.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("socket %s is in use by another process", path)
+	}
+	return os.Remove(path)
+}
+
+// listenAll opens a listener for every address, closing those already
+// opened if one fails
+func listenAll(ctx context.Context, addrs []confignet.AddrConfig) ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, len(addrs))
+	for _, addr := range addrs {
+		listener, err := listen(ctx, addr)
+		if err != nil {
+			for _, opened := range listeners {
+				opened.Close()
+			}
+			return nil, fmt.Errorf("failed to listen on %s: %w", addr.Endpoint, err)
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
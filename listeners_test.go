@@ -0,0 +1,182 @@
+package gleanreceiver
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config/confignet"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+)
+
+func TestValidateListenAddr(t *testing.T) {
+	tests := []struct {
+		name    string
+		addr    confignet.AddrConfig
+		wantErr bool
+	}{
+		{
+			name: "tcp",
+			addr: confignet.AddrConfig{Endpoint: "localhost:9888", Transport: confignet.TransportTypeTCP},
+		},
+		{
+			name: "default transport",
+			addr: confignet.AddrConfig{Endpoint: "localhost:9888"},
+		},
+		{
+			name: "unix socket",
+			addr: confignet.AddrConfig{Endpoint: "/var/run/glean.sock", Transport: confignet.TransportTypeUnix},
+		},
+		{
+			name:    "empty endpoint",
+			addr:    confignet.AddrConfig{Transport: confignet.TransportTypeTCP},
+			wantErr: true,
+		},
+		{
+			name:    "datagram transport",
+			addr:    confignet.AddrConfig{Endpoint: "localhost:9888", Transport: confignet.TransportTypeUDP},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateListenAddr(tt.addr)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestListenReplacesStaleSocket(t *testing.T) {
+	addr := confignet.AddrConfig{
+		Endpoint:  filepath.Join(t.TempDir(), "glean.sock"),
+		Transport: confignet.TransportTypeUnix,
+	}
+
+	// Leave the socket file behind, as a crashed process would
+	stale, err := listen(context.Background(), addr)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	listener, err := listen(context.Background(), addr)
+	require.NoError(t, err)
+	assert.NoError(t, listener.Close())
+}
+
+func TestListenKeepsSocketInUse(t *testing.T) {
+	dir := t.TempDir()
+	addr := confignet.AddrConfig{
+		Endpoint:  filepath.Join(dir, "glean.sock"),
+		Transport: confignet.TransportTypeUnix,
+	}
+
+	// A socket another process still accepts connections on is not taken over
+	active, err := listen(context.Background(), addr)
+	require.NoError(t, err)
+	defer active.Close()
+	_, err = listen(context.Background(), addr)
+	assert.ErrorContains(t, err, "in use")
+
+	// Files that are not sockets are never removed
+	file := filepath.Join(dir, "glean.conf")
+	require.NoError(t, os.WriteFile(file, []byte("keep"), 0o600))
+	_, err = listen(context.Background(), confignet.AddrConfig{Endpoint: file, Transport: confignet.TransportTypeUnix})
+	assert.ErrorContains(t, err, "not a socket")
+	assert.FileExists(t, file)
+}
+
+func TestListenAllClosesOnFailure(t *testing.T) {
+	busy, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer busy.Close()
+
+	free := confignet.AddrConfig{Endpoint: "localhost:19914"}
+	_, err = listenAll(context.Background(), []confignet.AddrConfig{
+		free,
+		{Endpoint: busy.Addr().String()},
+	})
+	require.Error(t, err)
+
+	// The first listener was released
+	listener, err := listen(context.Background(), free)
+	require.NoError(t, err)
+	listener.Close()
+}
+
+func TestReceiverAdditionalEndpoints(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "glean.sock")
+	cfg := &Config{
+		Path: "/test",
+		AdditionalEndpoints: []confignet.AddrConfig{
+			{Endpoint: "localhost:19913", Transport: confignet.TransportTypeTCP},
+			{Endpoint: socket, Transport: confignet.TransportTypeUnix},
+		},
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19912"
+
+	metricsSink := new(consumertest.MetricsSink)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		metricsSink,
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	unixClient := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+
+	for i, target := range []struct {
+		client *http.Client
+		host   string
+	}{
+		{client: http.DefaultClient, host: "localhost:19912"},
+		{client: http.DefaultClient, host: "localhost:19913"},
+		{client: unixClient, host: "glean"},
+	} {
+		resp, err := target.client.Post(
+			"http://"+target.host+"/test/test-app/metrics/1/doc-1",
+			"application/json",
+			bytes.NewBufferString(envelopePingBody),
+		)
+		require.NoError(t, err, target.host)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, target.host)
+		assert.Eventually(t, func() bool {
+			return len(metricsSink.AllMetrics()) == i+1
+		}, time.Second, 10*time.Millisecond)
+	}
+
+	// The socket is removed on shutdown
+	require.NoError(t, receiver.Shutdown(ctx))
+	_, err = net.Dial("unix", socket)
+	assert.Error(t, err)
+}
@@ -0,0 +1,79 @@
+package gleanreceiver
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// requestHeaderAttributePrefix is the semantic convention prefix for HTTP
+// request headers
+const requestHeaderAttributePrefix = "http.request.header."
+
+// mapRequest adds the submission URL parameters, submission time, source tag
+// routing and the configured request headers. The document ID and submission
+// time are unique per ping, so they go on the record attributes; the rest are
+// low-cardinality resource attributes.
+func (m *attributeMapper) mapRequest(attrs mappedAttributes, gleanRequest *GleanPingRequest) {
+	putNonEmptyStr(attrs.record, "document.id", gleanRequest.DocumentID)
+	if !gleanRequest.SubmissionTime.IsZero() {
+		attrs.record.PutStr("glean.submission_timestamp", gleanRequest.SubmissionTime.UTC().Format(time.RFC3339Nano))
+	}
+	putNonEmptyStr(attrs.resource, "document.type", gleanRequest.DocumentType)
+	putNonEmptyStr(attrs.resource, "document.version", gleanRequest.DocumentVersion)
+	if len(gleanRequest.SourceTags) > 0 {
+		tags := attrs.resource.PutEmptySlice("glean.source_tags")
+		for _, tag := range gleanRequest.SourceTags {
+			tags.AppendEmpty().SetStr(tag)
+		}
+	}
+	putNonEmptyStr(attrs.resource, "glean.route", gleanRequest.Route)
+	if m == nil {
+		return
+	}
+	for _, header := range m.requestHeaders {
+		if value := gleanRequest.Headers.Get(header); value != "" {
+			attrs.resource.PutStr(requestHeaderAttributePrefix+strings.ToLower(header), value)
+		}
+	}
+}
+
+// putNonEmptyStr adds a string attribute unless value is empty
+func putNonEmptyStr(attrs pcommon.Map, key string, value string) {
+	if value != "" {
+		attrs.PutStr(key, value)
+	}
+}
+
+// withRequestMetadata returns ctx with the request metadata added to its
+// client.Metadata so downstream processors can route on it. Existing
+// metadata, such as headers propagated by include_metadata, is kept.
+func withRequestMetadata(ctx context.Context, gleanRequest GleanPingRequest, headers []string) context.Context {
+	info := client.FromContext(ctx)
+
+	md := make(map[string][]string)
+	for key := range info.Metadata.Keys() {
+		md[key] = info.Metadata.Get(key)
+	}
+	md["namespace"] = []string{gleanRequest.Namespace}
+	md["document_type"] = []string{gleanRequest.DocumentType}
+	md["document_version"] = []string{gleanRequest.DocumentVersion}
+	md["document_id"] = []string{gleanRequest.DocumentID}
+	if !gleanRequest.SubmissionTime.IsZero() {
+		md["submission_timestamp"] = []string{gleanRequest.SubmissionTime.UTC().Format(time.RFC3339Nano)}
+	}
+	if gleanRequest.Route != "" {
+		md["glean.route"] = []string{gleanRequest.Route}
+	}
+	for _, header := range headers {
+		if values := gleanRequest.Headers.Values(header); len(values) > 0 {
+			md[header] = values
+		}
+	}
+
+	info.Metadata = client.NewMetadata(md)
+	return client.NewContext(ctx, info)
+}
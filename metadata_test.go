@@ -0,0 +1,85 @@
+package gleanreceiver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/client"
+)
+
+func newTestGleanRequest() GleanPingRequest {
+	headers := http.Header{}
+	headers.Set("X-Debug-ID", "my-debug-tag")
+	headers.Set("User-Agent", "Glean/60.0.0 (Kotlin on Android)")
+	headers.Set("Authorization", "Bearer secret")
+	return GleanPingRequest{
+		Namespace:       "org-mozilla-fenix",
+		DocumentType:    "metrics",
+		DocumentVersion: "1",
+		DocumentID:      "c641eacf-c30c-4171-b403-f077724e848a",
+		Headers:         headers,
+		SubmissionTime:  time.Date(2024, 1, 28, 10, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestAttributeMapperMapRequest(t *testing.T) {
+	mapper, err := newAttributeMapper(&Config{RequestHeaders: []string{"X-Debug-ID", "User-Agent", "X-Source-Tags"}})
+	require.NoError(t, err)
+
+	gleanRequest := newTestGleanRequest()
+	attrs := newMappedAttributes()
+	mapper.mapRequest(attrs, &gleanRequest)
+
+	assert.Equal(t, map[string]any{
+		"document.type":                  "metrics",
+		"document.version":               "1",
+		"http.request.header.x-debug-id": "my-debug-tag",
+		"http.request.header.user-agent": "Glean/60.0.0 (Kotlin on Android)",
+	}, attrs.resource.AsRaw())
+	assert.Equal(t, map[string]any{
+		"document.id":                "c641eacf-c30c-4171-b403-f077724e848a",
+		"glean.submission_timestamp": "2024-01-28T10:00:00Z",
+	}, attrs.record.AsRaw())
+}
+
+func TestWithRequestMetadata(t *testing.T) {
+	// Metadata already propagated by include_metadata is kept
+	ctx := client.NewContext(context.Background(), client.Info{
+		Metadata: client.NewMetadata(map[string][]string{"X-Tenant": {"mobile"}}),
+	})
+
+	ctx = withRequestMetadata(ctx, newTestGleanRequest(), []string{"X-Debug-ID"})
+	md := client.FromContext(ctx).Metadata
+
+	assert.Equal(t, []string{"mobile"}, md.Get("X-Tenant"))
+	assert.Equal(t, []string{"org-mozilla-fenix"}, md.Get("namespace"))
+	assert.Equal(t, []string{"metrics"}, md.Get("document_type"))
+	assert.Equal(t, []string{"1"}, md.Get("document_version"))
+	assert.Equal(t, []string{"c641eacf-c30c-4171-b403-f077724e848a"}, md.Get("document_id"))
+	assert.Equal(t, []string{"2024-01-28T10:00:00Z"}, md.Get("submission_timestamp"))
+	assert.Equal(t, []string{"my-debug-tag"}, md.Get("X-Debug-ID"))
+	assert.Nil(t, md.Get("Authorization"))
+}
+
+func TestAttributeMapperMapRequestSourceTags(t *testing.T) {
+	gleanRequest := newTestGleanRequest()
+	gleanRequest.SourceTags = []string{"qa", "perf"}
+	gleanRequest.Route = "qa"
+
+	attrs := newMappedAttributes()
+	(*attributeMapper)(nil).mapRequest(attrs, &gleanRequest)
+
+	tags, exists := attrs.resource.Get("glean.source_tags")
+	require.True(t, exists)
+	assert.Equal(t, []any{"qa", "perf"}, tags.Slice().AsRaw())
+	route, exists := attrs.resource.Get("glean.route")
+	require.True(t, exists)
+	assert.Equal(t, "qa", route.Str())
+
+	md := client.FromContext(withRequestMetadata(context.Background(), gleanRequest, nil)).Metadata
+	assert.Equal(t, []string{"qa"}, md.Get("glean.route"))
+}
@@ -0,0 +1,286 @@
+package gleanreceiver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// pendingPingsReader tracks the files of Glean SDK pending_pings directories
+// that still need to be ingested
+type pendingPingsReader struct {
+	directories     []string
+	deleteProcessed bool
+
+	// processed holds the modification time of every file already ingested
+	processed map[string]time.Time
+
+	// deliveries holds what earlier scans did for files refused with a
+	// server error, so they are not forwarded or converted again
+	deliveries map[pendingPingFile]*pingDelivery
+}
+
+// pendingPingFile is a ping file waiting to be ingested
+type pendingPingFile struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// newPendingPingsReader creates a new instance of pendingPingsReader. It
+// returns nil when no directories are configured.
+func newPendingPingsReader(cfg PendingPingsConfig) *pendingPingsReader {
+	if len(cfg.Directories) == 0 {
+		return nil
+	}
+	return &pendingPingsReader{
+		directories:     cfg.Directories,
+		deleteProcessed: cfg.DeleteProcessed,
+		processed:       make(map[string]time.Time),
+		deliveries:      make(map[pendingPingFile]*pingDelivery),
+	}
+}
+
+// scan returns the files that are new or changed since they were last
+// ingested, oldest first
+func (p *pendingPingsReader) scan() ([]pendingPingFile, error) {
+	var files []pendingPingFile
+	var errs []error
+	present := make(map[string]bool)
+
+	for _, dir := range p.directories {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, entry := range entries {
+			// Skip subdirectories and temporary files
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			present[path] = true
+			if modTime, ok := p.processed[path]; ok && modTime.Equal(info.ModTime()) {
+				continue
+			}
+			files = append(files, pendingPingFile{path: path, modTime: info.ModTime(), size: info.Size()})
+		}
+	}
+
+	// Forget files that no longer exist
+	for path := range p.processed {
+		if !present[path] {
+			delete(p.processed, path)
+		}
+	}
+	for file := range p.deliveries {
+		if !present[file.path] {
+			delete(p.deliveries, file)
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	return files, errors.Join(errs...)
+}
+
+// delivery returns what earlier scans did for file
+func (p *pendingPingsReader) delivery(file pendingPingFile) *pingDelivery {
+	delivery, ok := p.deliveries[file]
+	if !ok {
+		delivery = newPingDelivery()
+		p.deliveries[file] = delivery
+	}
+	return delivery
+}
+
+// markProcessed deletes or remembers a file that must not be ingested again
+func (p *pendingPingsReader) markProcessed(file pendingPingFile) error {
+	delete(p.deliveries, file)
+	if p.deleteProcessed {
+		return os.Remove(file.path)
+	}
+	p.processed[file.path] = file.modTime
+	return nil
+}
+
+// parsePendingPing parses a file in the Glean SDK pending_pings format: the
+// submission path on the first line, the JSON body on the second and an
+// optional metadata line. Recent SDKs write metadata as
+// {"headers": {...}, ...} while older ones write the headers object itself.
+func parsePendingPing(data []byte) (GleanPingRequest, []byte, error) {
+	lines := bytes.Split(data, []byte("\n"))
+	for i := range lines {
+		lines[i] = bytes.TrimSuffix(lines[i], []byte("\r"))
+	}
+	if len(lines) < 2 || len(bytes.TrimSpace(lines[0])) == 0 || len(bytes.TrimSpace(lines[1])) == 0 {
+		return GleanPingRequest{}, nil, errors.New("expected a path line and a body line")
+	}
+
+	gleanRequest, err := parseSubmissionPath(string(lines[0]))
+	if err != nil {
+		return GleanPingRequest{}, nil, err
+	}
+
+	gleanRequest.Headers = http.Header{}
+	if len(lines) > 2 && len(bytes.TrimSpace(lines[2])) > 0 {
+		headers, err := parsePendingPingHeaders(lines[2])
+		if err != nil {
+			return GleanPingRequest{}, nil, err
+		}
+		gleanRequest.Headers = headers
+	}
+
+	return gleanRequest, lines[1], nil
+}
+
+// parsePendingPingHeaders parses the metadata line of a pending ping file
+func parsePendingPingHeaders(line []byte) (http.Header, error) {
+	var metadata map[string]any
+	if err := json.Unmarshal(line, &metadata); err != nil {
+		return nil, fmt.Errorf("invalid metadata line: %w", err)
+	}
+
+	values := metadata
+	if nested, ok := metadata["headers"].(map[string]any); ok {
+		values = nested
+	}
+
+	headers := http.Header{}
+	for name, value := range values {
+		if s, ok := value.(string); ok {
+			headers.Set(name, s)
+		}
+	}
+	return headers, nil
+}
+
+// parseSubmissionPath reads the namespace, document type, document version
+// and document ID from the last four segments of a submission path such as
+// /submit/{namespace}/{document_type}/{document_version}/{document_id}
+func parseSubmissionPath(path string) (GleanPingRequest, error) {
+	path = strings.TrimSpace(path)
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) < 4 {
+		return GleanPingRequest{}, fmt.Errorf("invalid submission path %q", path)
+	}
+
+	segments = segments[len(segments)-4:]
+	return GleanPingRequest{
+		Namespace:       segments[0],
+		DocumentType:    segments[1],
+		DocumentVersion: segments[2],
+		DocumentID:      segments[3],
+	}, nil
+}
+
+// pollPendingPings ingests pending ping files until the receiver shuts down.
+// Shutting down cancels the ping being ingested and the rest of the scan.
+func (r *gleanReceiver) pollPendingPings() {
+	defer r.background.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-r.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(r.cfg.PendingPings.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		r.ingestPendingPings(ctx)
+		select {
+		case <-ticker.C:
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// ingestPendingPings runs every new pending ping file through the same path
+// as HTTP pings. Files refused with a server error are retried on the next
+// scan, without forwarding them or sending accepted signals again; all
+// others are marked as processed.
+func (r *gleanReceiver) ingestPendingPings(ctx context.Context) {
+	files, err := r.pendingPings.scan()
+	if err != nil {
+		r.logger.Warn("Failed to scan pending_pings directories", zap.Error(err))
+	}
+
+	maxBytes := r.cfg.maxDecompressedBytes()
+	for _, file := range files {
+		// Leave the rest of the backlog for the next start
+		select {
+		case <-r.stop:
+			return
+		default:
+		}
+
+		// Refuse oversized files before reading them, as oversized HTTP
+		// pings are
+		if file.size > maxBytes {
+			r.telemetry.recordLimitedPing(ctx, limitDecompressedBytes, limitActionReject)
+			r.logger.Warn("Pending ping refused", zap.String("file", file.path), zap.Error(errPingTooLarge))
+			if err := r.pendingPings.markProcessed(file); err != nil {
+				r.logger.Warn("Failed to remove pending ping", zap.String("file", file.path), zap.Error(err))
+			}
+			continue
+		}
+
+		data, err := os.ReadFile(file.path)
+		if err != nil {
+			r.logger.Warn("Failed to read pending ping", zap.String("file", file.path), zap.Error(err))
+			continue
+		}
+
+		gleanRequest, body, err := parsePendingPing(data)
+		if err != nil {
+			r.logger.Warn("Skipping malformed pending ping", zap.String("file", file.path), zap.Error(err))
+		} else {
+			gleanRequest.SubmissionTime = time.Now()
+			gleanRequest.Delivery = r.pendingPings.delivery(file)
+			err = r.ingestPing(ctx, gleanRequest, func() ([]byte, error) { return body, nil })
+			if pingStatus(err) >= http.StatusInternalServerError {
+				r.logger.Warn("Failed to ingest pending ping, retrying later", zap.String("file", file.path), zap.Error(err))
+				continue
+			}
+			if err != nil {
+				r.logger.Warn("Pending ping refused", zap.String("file", file.path), zap.Error(err))
+			}
+		}
+
+		if err := r.pendingPings.markProcessed(file); err != nil {
+			r.logger.Warn("Failed to remove pending ping", zap.String("file", file.path), zap.Error(err))
+		}
+	}
+}
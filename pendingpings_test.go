@@ -0,0 +1,348 @@
+package gleanreceiver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+)
+
+const pendingPingBody = `{"ping_info":{"seq":1,"start_time":"2024-01-01T00:00:00Z","end_time":"2024-01-01T01:00:00Z"},"client_info":{"client_id":"test-client"},"metrics":{"counter":{"test_counter":5}}}`
+
+func TestParsePendingPing(t *testing.T) {
+	tests := []struct {
+		name            string
+		content         string
+		expectedHeaders http.Header
+		expectError     bool
+	}{
+		{
+			name:            "path and body",
+			content:         "/submit/test-app/metrics/1/doc-1\n" + pendingPingBody + "\n",
+			expectedHeaders: http.Header{},
+		},
+		{
+			name:    "metadata line",
+			content: "/submit/test-app/metrics/1/doc-1\n" + pendingPingBody + "\n" + `{"headers":{"x-debug-id":"qa","X-Source-Tags":"automation"},"body_has_info_sections":true}`,
+			expectedHeaders: http.Header{
+				"X-Debug-Id":    {"qa"},
+				"X-Source-Tags": {"automation"},
+			},
+		},
+		{
+			name:    "legacy headers line",
+			content: "/submit/test-app/metrics/1/doc-1\r\n" + pendingPingBody + "\r\n" + `{"X-Debug-ID":"qa"}` + "\r\n",
+			expectedHeaders: http.Header{
+				"X-Debug-Id": {"qa"},
+			},
+		},
+		{
+			name:        "missing body",
+			content:     "/submit/test-app/metrics/1/doc-1\n",
+			expectError: true,
+		},
+		{
+			name:        "invalid path",
+			content:     "/submit/test-app\n" + pendingPingBody,
+			expectError: true,
+		},
+		{
+			name:        "invalid metadata",
+			content:     "/submit/test-app/metrics/1/doc-1\n" + pendingPingBody + "\nnot json",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gleanRequest, body, err := parsePendingPing([]byte(tt.content))
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "test-app", gleanRequest.Namespace)
+			assert.Equal(t, "metrics", gleanRequest.DocumentType)
+			assert.Equal(t, "1", gleanRequest.DocumentVersion)
+			assert.Equal(t, "doc-1", gleanRequest.DocumentID)
+			assert.Equal(t, tt.expectedHeaders, gleanRequest.Headers)
+			assert.JSONEq(t, pendingPingBody, string(body))
+		})
+	}
+}
+
+func TestParseSubmissionPath(t *testing.T) {
+	for _, path := range []string{
+		"/submit/test-app/metrics/1/doc-1",
+		"test-app/metrics/1/doc-1",
+		"https://incoming.example.com/submit/test-app/metrics/1/doc-1?v=1",
+	} {
+		gleanRequest, err := parseSubmissionPath(path)
+		require.NoError(t, err, path)
+		assert.Equal(t, GleanPingRequest{
+			Namespace:       "test-app",
+			DocumentType:    "metrics",
+			DocumentVersion: "1",
+			DocumentID:      "doc-1",
+		}, gleanRequest, path)
+	}
+
+	_, err := parseSubmissionPath("/submit/test-app/metrics")
+	assert.Error(t, err)
+}
+
+func TestPendingPingsReaderScan(t *testing.T) {
+	assert.Nil(t, newPendingPingsReader(PendingPingsConfig{}))
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "doc-1"), []byte("ping"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".tmp"), []byte("ping"), 0o600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "events"), 0o700))
+
+	reader := newPendingPingsReader(PendingPingsConfig{Directories: []string{dir}})
+	files, err := reader.scan()
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, filepath.Join(dir, "doc-1"), files[0].path)
+
+	// Processed files are skipped until they change
+	require.NoError(t, reader.markProcessed(files[0]))
+	files, err = reader.scan()
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "doc-1"), later, later))
+	files, err = reader.scan()
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	// Missing directories are reported
+	reader = newPendingPingsReader(PendingPingsConfig{Directories: []string{filepath.Join(dir, "missing")}})
+	_, err = reader.scan()
+	assert.Error(t, err)
+}
+
+func TestReceiverPendingPings(t *testing.T) {
+	dir := t.TempDir()
+	writePing := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	writePing("doc-1", "/submit/test-app/metrics/1/doc-1\n"+pendingPingBody+"\n"+`{"headers":{"X-Debug-ID":"qa"}}`)
+	writePing("doc-2", "not a ping")
+
+	cfg := &Config{
+		Path:           "/test",
+		RequestHeaders: []string{"X-Debug-ID"},
+		PendingPings: PendingPingsConfig{
+			Directories:     []string{dir},
+			PollInterval:    10 * time.Millisecond,
+			DeleteProcessed: true,
+		},
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19907"
+
+	metricsSink := new(consumertest.MetricsSink)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		metricsSink,
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	assert.Eventually(t, func() bool {
+		return len(metricsSink.AllMetrics()) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	md := client.FromContext(metricsSink.Contexts()[0]).Metadata
+	assert.Equal(t, []string{"doc-1"}, md.Get("document_id"))
+	attrs := metricsSink.AllMetrics()[0].ResourceMetrics().At(0).Resource().Attributes()
+	debugID, exists := attrs.Get("http.request.header.x-debug-id")
+	assert.True(t, exists)
+	assert.Equal(t, "qa", debugID.Str())
+
+	// Both the ingested and the malformed file are removed
+	assert.Eventually(t, func() bool {
+		entries, err := os.ReadDir(dir)
+		return err == nil && len(entries) == 0
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// New files are picked up on the next poll
+	writePing("doc-3", "/submit/test-app/metrics/1/doc-3\n"+pendingPingBody)
+	assert.Eventually(t, func() bool {
+		return len(metricsSink.AllMetrics()) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestReceiverPendingPingsRetryDoesNotForwardAgain(t *testing.T) {
+	var forwarded atomic.Int32
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer downstream.Close()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "doc-1"), []byte("/submit/test-app/metrics/1/doc-1\n"+pendingPingBody), 0o600))
+
+	cfg := &Config{
+		Path:       "/test",
+		ForwardURL: downstream.URL,
+		PendingPings: PendingPingsConfig{
+			Directories:     []string{dir},
+			PollInterval:    10 * time.Millisecond,
+			DeleteProcessed: true,
+		},
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19932"
+
+	// The file is refused with a server error on the first three scans
+	metricsSink := new(flakyMetricsSink)
+	metricsSink.failures.Store(3)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		metricsSink,
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	assert.Eventually(t, func() bool {
+		return len(metricsSink.AllMetrics()) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(1), forwarded.Load())
+}
+
+// blockingMetricsSink blocks every ConsumeMetrics call until its context
+// is cancelled
+type blockingMetricsSink struct {
+	consumertest.MetricsSink
+	calls atomic.Int32
+}
+
+func (s *blockingMetricsSink) ConsumeMetrics(ctx context.Context, _ pmetric.Metrics) error {
+	s.calls.Add(1)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestReceiverPendingPingsShutdown(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"doc-1", "doc-2", "doc-3"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("/submit/test-app/metrics/1/"+name+"\n"+pendingPingBody), 0o600))
+	}
+
+	cfg := &Config{
+		Path: "/test",
+		PendingPings: PendingPingsConfig{
+			Directories:     []string{dir},
+			PollInterval:    10 * time.Millisecond,
+			DeleteProcessed: true,
+		},
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19933"
+
+	metricsSink := new(blockingMetricsSink)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		metricsSink,
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return metricsSink.calls.Load() == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// Shutdown cancels the blocked ping and leaves the rest of the backlog
+	done := make(chan error)
+	go func() { done <- receiver.Shutdown(ctx) }()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Shutdown waited for the pending pings backlog")
+	}
+	assert.Equal(t, int32(1), metricsSink.calls.Load())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+}
+
+func TestReceiverPendingPingsTooLarge(t *testing.T) {
+	dir := t.TempDir()
+	content := "/submit/test-app/metrics/1/doc-1\n" + pendingPingBody
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "doc-1"), []byte(content), 0o600))
+
+	cfg := &Config{
+		Path: "/test",
+		Limits: LimitsConfig{
+			MaxDecompressedBytes: int64(len(content) - 1),
+		},
+		PendingPings: PendingPingsConfig{
+			Directories:     []string{dir},
+			PollInterval:    10 * time.Millisecond,
+			DeleteProcessed: true,
+		},
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19927"
+
+	metricsSink := new(consumertest.MetricsSink)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		metricsSink,
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	// The oversized file is refused and removed without being ingested
+	assert.Eventually(t, func() bool {
+		entries, err := os.ReadDir(dir)
+		return err == nil && len(entries) == 0
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Empty(t, metricsSink.AllMetrics())
+}
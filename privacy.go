@@ -0,0 +1,111 @@
+package gleanreceiver
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+// Identifier modes for client_id and session_id
+const (
+	identifierModeNone     = "none"
+	identifierModeHash     = "hash"
+	identifierModeTruncate = "truncate"
+	identifierModeDrop     = "drop"
+)
+
+// identifierPseudonymizer rewrites persistent client identifiers before a
+// ping is converted, so every signal exports the same pseudonymized values
+type identifierPseudonymizer struct {
+	mode           string
+	key            []byte
+	truncateLength int
+}
+
+// newIdentifierPseudonymizer creates a new instance of identifierPseudonymizer.
+// It returns nil when identifiers are exported as-is.
+func newIdentifierPseudonymizer(cfg PrivacyConfig) (*identifierPseudonymizer, error) {
+	switch cfg.IdentifierMode {
+	case "", identifierModeNone:
+		return nil, nil
+	case identifierModeHash:
+		key := string(cfg.HashKey)
+		if key == "" && cfg.HashKeyEnv != "" {
+			key = os.Getenv(cfg.HashKeyEnv)
+		}
+		if key == "" {
+			return nil, fmt.Errorf("no hash key configured for client identifier pseudonymization")
+		}
+		return &identifierPseudonymizer{mode: cfg.IdentifierMode, key: []byte(key)}, nil
+	case identifierModeTruncate, identifierModeDrop:
+		return &identifierPseudonymizer{mode: cfg.IdentifierMode, truncateLength: cfg.TruncateLength}, nil
+	default:
+		return nil, fmt.Errorf("invalid identifier mode %q", cfg.IdentifierMode)
+	}
+}
+
+// apply pseudonymizes the client_id and session_id of clientInfo in place
+func (p *identifierPseudonymizer) apply(clientInfo *ClientInfo) {
+	clientInfo.ClientID = p.pseudonymize(clientInfo.ClientID)
+	clientInfo.SessionID = p.pseudonymize(clientInfo.SessionID)
+}
+
+// pseudonymize returns the exported form of a single identifier
+func (p *identifierPseudonymizer) pseudonymize(id string) string {
+	if id == "" {
+		return id
+	}
+
+	switch p.mode {
+	case identifierModeHash:
+		mac := hmac.New(sha256.New, p.key)
+		mac.Write([]byte(id))
+		return hex.EncodeToString(mac.Sum(nil))
+	case identifierModeTruncate:
+		if len(id) > p.truncateLength {
+			return id[:p.truncateLength]
+		}
+		return id
+	case identifierModeDrop:
+		return ""
+	default:
+		return id
+	}
+}
+
+// clientKeyer derives the keys per-client state, such as open sessions and
+// rate limit buckets, is held under. Keys are an HMAC of the raw client_id
+// with a secret drawn when the receiver is created, so state can be purged
+// whatever the identifier mode and raw identifiers are not kept in memory.
+type clientKeyer struct {
+	secret []byte
+}
+
+// newClientKeyer creates a new instance of clientKeyer with a random secret
+func newClientKeyer() *clientKeyer {
+	secret := make([]byte, sha256.Size)
+	_, _ = rand.Read(secret)
+	return &clientKeyer{secret: secret}
+}
+
+// sessionKey returns the state key of a session from the client key,
+// namespace and raw session_id, or "" if session_id is empty
+func (k *clientKeyer) sessionKey(clientKey, namespace, sessionID string) string {
+	if sessionID == "" {
+		return ""
+	}
+	return k.key(clientKey + "\x00" + namespace + "\x00" + sessionID)
+}
+
+// key returns the state key of a raw client_id, or "" if it is empty
+func (k *clientKeyer) key(clientID string) string {
+	if clientID == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, k.secret)
+	mac.Write([]byte(clientID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,91 @@
+package gleanreceiver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentifierPseudonymizer(t *testing.T) {
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("c641eacf-c30c-4171-b403-f077724e848a"))
+	expectedHash := hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name              string
+		cfg               PrivacyConfig
+		expectedClientID  string
+		expectedSessionID string
+	}{
+		{
+			name:              "hash",
+			cfg:               PrivacyConfig{IdentifierMode: identifierModeHash, HashKey: "secret"},
+			expectedClientID:  expectedHash,
+			expectedSessionID: "",
+		},
+		{
+			name:              "truncate",
+			cfg:               PrivacyConfig{IdentifierMode: identifierModeTruncate, TruncateLength: 8},
+			expectedClientID:  "c641eacf",
+			expectedSessionID: "",
+		},
+		{
+			name:              "drop",
+			cfg:               PrivacyConfig{IdentifierMode: identifierModeDrop},
+			expectedClientID:  "",
+			expectedSessionID: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pseudonymizer, err := newIdentifierPseudonymizer(tt.cfg)
+			require.NoError(t, err)
+			require.NotNil(t, pseudonymizer)
+
+			clientInfo := ClientInfo{ClientID: "c641eacf-c30c-4171-b403-f077724e848a"}
+			pseudonymizer.apply(&clientInfo)
+			assert.Equal(t, tt.expectedClientID, clientInfo.ClientID)
+			assert.Equal(t, tt.expectedSessionID, clientInfo.SessionID)
+		})
+	}
+}
+
+func TestIdentifierPseudonymizerHashSessionID(t *testing.T) {
+	pseudonymizer, err := newIdentifierPseudonymizer(PrivacyConfig{IdentifierMode: identifierModeHash, HashKey: "secret"})
+	require.NoError(t, err)
+
+	clientInfo := ClientInfo{ClientID: "client", SessionID: "session"}
+	pseudonymizer.apply(&clientInfo)
+
+	assert.Len(t, clientInfo.ClientID, 64)
+	assert.Len(t, clientInfo.SessionID, 64)
+	assert.NotEqual(t, clientInfo.ClientID, clientInfo.SessionID)
+
+	// Hashing is deterministic so series stay joinable
+	assert.Equal(t, clientInfo.ClientID, pseudonymizer.pseudonymize("client"))
+}
+
+func TestIdentifierPseudonymizerKeyFromEnv(t *testing.T) {
+	t.Setenv("GLEAN_TEST_HASH_KEY", "secret")
+
+	fromEnv, err := newIdentifierPseudonymizer(PrivacyConfig{IdentifierMode: identifierModeHash, HashKeyEnv: "GLEAN_TEST_HASH_KEY"})
+	require.NoError(t, err)
+	fromConfig, err := newIdentifierPseudonymizer(PrivacyConfig{IdentifierMode: identifierModeHash, HashKey: "secret"})
+	require.NoError(t, err)
+
+	assert.Equal(t, fromConfig.pseudonymize("client"), fromEnv.pseudonymize("client"))
+
+	_, err = newIdentifierPseudonymizer(PrivacyConfig{IdentifierMode: identifierModeHash, HashKeyEnv: "GLEAN_TEST_MISSING_KEY"})
+	assert.Error(t, err)
+}
+
+func TestIdentifierPseudonymizerNone(t *testing.T) {
+	pseudonymizer, err := newIdentifierPseudonymizer(PrivacyConfig{IdentifierMode: identifierModeNone})
+	require.NoError(t, err)
+	assert.Nil(t, pseudonymizer)
+}
@@ -0,0 +1,259 @@
+package gleanreceiver
+
+import (
+	"container/list"
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Keys rate limits apply to
+const (
+	rateLimitKeyClientIP  = "client_ip"
+	rateLimitKeyClientID  = "client_id"
+	rateLimitKeyNamespace = "namespace"
+)
+
+// rateLimiter throttles HTTP pings with token buckets kept per key value
+type rateLimiter struct {
+	rules          []*rateLimitRule
+	clientIPHeader string
+	trustedProxies int
+}
+
+// rateLimitRule holds the token buckets of one rule. Only the most recently
+// used maxKeys buckets are kept.
+type rateLimitRule struct {
+	key     string
+	rate    float64
+	burst   float64
+	maxKeys int
+
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	recent  *list.List
+}
+
+// tokenBucket is the bucket of one key value
+type tokenBucket struct {
+	value   string
+	tokens  float64
+	updated time.Time
+}
+
+// newRateLimiter returns nil when no rule is configured
+func newRateLimiter(cfg RateLimitConfig) *rateLimiter {
+	if len(cfg.Rules) == 0 {
+		return nil
+	}
+	limiter := &rateLimiter{
+		clientIPHeader: cfg.ClientIPHeader,
+		trustedProxies: max(cfg.TrustedProxies, 1),
+	}
+	for _, rule := range cfg.Rules {
+		burst := float64(rule.Burst)
+		if burst == 0 {
+			burst = max(rule.Rate, 1)
+		}
+		limiter.rules = append(limiter.rules, &rateLimitRule{
+			key:     rule.Key,
+			rate:    rule.Rate,
+			burst:   burst,
+			maxKeys: cfg.MaxKeys,
+			buckets: make(map[string]*list.Element),
+			recent:  list.New(),
+		})
+	}
+	return limiter
+}
+
+// limits reports whether a rule applies to key
+func (l *rateLimiter) limits(key string) bool {
+	for _, rule := range l.rules {
+		if rule.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// rateLimitValue is the value a ping has for a rate limit key
+type rateLimitValue struct {
+	key   string
+	value string
+}
+
+// allow takes a token from the bucket of every rule on the keys of limits.
+// Every bucket is checked before any token is taken, so a request refused by
+// one rule does not drain the others. When a bucket is empty, it returns
+// false, the key of the refusing rule and how long until its bucket has a
+// token again. Empty values are not limited.
+func (l *rateLimiter) allow(limits []rateLimitValue, now time.Time) (string, time.Duration, bool) {
+	// Rules are locked in configuration order so concurrent calls cannot
+	// deadlock
+	var buckets []*tokenBucket
+	for _, rule := range l.rules {
+		value := rule.valueOf(limits)
+		if value == "" {
+			continue
+		}
+		rule.mu.Lock()
+		defer rule.mu.Unlock()
+		bucket := rule.refill(value, now)
+		if bucket.tokens < 1 {
+			return rule.key, time.Duration((1 - bucket.tokens) / rule.rate * float64(time.Second)), false
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	for _, bucket := range buckets {
+		bucket.tokens--
+	}
+	return "", 0, true
+}
+
+// giveBack returns the tokens allow took for limits, for a ping refused by a
+// rule checked later. Buckets evicted in the meantime are not recreated.
+func (l *rateLimiter) giveBack(limits []rateLimitValue) {
+	for _, rule := range l.rules {
+		if value := rule.valueOf(limits); value != "" {
+			rule.giveBack(value)
+		}
+	}
+}
+
+// purge drops the buckets held for value on key
+func (l *rateLimiter) purge(key, value string) {
+	for _, rule := range l.rules {
+		if rule.key == key {
+			rule.remove(value)
+		}
+	}
+}
+
+// clientIP returns the address of the client sending req. With a configured
+// header such as X-Forwarded-For, it is the entry appended by the outermost
+// trusted proxy, counted from the right: clients can send any entries to the
+// left of it.
+func (l *rateLimiter) clientIP(req *http.Request) string {
+	if l.clientIPHeader != "" {
+		// Proxies may append a header line of their own instead of an entry
+		var entries []string
+		for _, line := range req.Header.Values(l.clientIPHeader) {
+			entries = append(entries, strings.Split(line, ",")...)
+		}
+		if len(entries) >= l.trustedProxies {
+			if entry := strings.TrimSpace(entries[len(entries)-l.trustedProxies]); entry != "" {
+				return entry
+			}
+		}
+	}
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		return host
+	}
+	return req.RemoteAddr
+}
+
+// refill returns the bucket of value, refilled at rate since it was last
+// used. rule.mu must be held.
+func (rule *rateLimitRule) refill(value string, now time.Time) *tokenBucket {
+	if element, ok := rule.buckets[value]; ok {
+		rule.recent.MoveToFront(element)
+		bucket := element.Value.(*tokenBucket)
+		elapsed := now.Sub(bucket.updated).Seconds()
+		bucket.tokens = min(rule.burst, bucket.tokens+max(elapsed, 0)*rule.rate)
+		bucket.updated = now
+		return bucket
+	}
+
+	bucket := &tokenBucket{value: value, tokens: rule.burst, updated: now}
+	rule.buckets[value] = rule.recent.PushFront(bucket)
+	// Evict the least recently used bucket once over the limit
+	if rule.recent.Len() > rule.maxKeys {
+		oldest := rule.recent.Back()
+		rule.recent.Remove(oldest)
+		delete(rule.buckets, oldest.Value.(*tokenBucket).value)
+	}
+	return bucket
+}
+
+// valueOf returns the value limits have for the key of the rule, or an
+// empty string
+func (rule *rateLimitRule) valueOf(limits []rateLimitValue) string {
+	for _, limit := range limits {
+		if limit.key == rule.key {
+			return limit.value
+		}
+	}
+	return ""
+}
+
+// giveBack returns a token to the bucket of value if it is still held
+func (rule *rateLimitRule) giveBack(value string) {
+	rule.mu.Lock()
+	defer rule.mu.Unlock()
+	if element, ok := rule.buckets[value]; ok {
+		bucket := element.Value.(*tokenBucket)
+		bucket.tokens = min(rule.burst, bucket.tokens+1)
+	}
+}
+
+// remove drops the bucket of value
+func (rule *rateLimitRule) remove(value string) {
+	rule.mu.Lock()
+	defer rule.mu.Unlock()
+	if element, ok := rule.buckets[value]; ok {
+		rule.recent.Remove(element)
+		delete(rule.buckets, value)
+	}
+}
+
+// rateLimited reports whether rate limits apply to a ping. Deletion requests
+// are never throttled, as clients drop pings refused with a 4xx status.
+func (r *gleanReceiver) rateLimited(gleanRequest GleanPingRequest) bool {
+	return r.rateLimiter != nil && gleanRequest.DocumentType != deletionRequestDocumentType
+}
+
+// requestLimits returns the client IP and namespace values of an HTTP ping,
+// known before its body is read
+func requestLimits(gleanRequest GleanPingRequest) []rateLimitValue {
+	return []rateLimitValue{
+		{rateLimitKeyClientIP, gleanRequest.ClientIP},
+		{rateLimitKeyNamespace, gleanRequest.Namespace},
+	}
+}
+
+// throttleRequest applies the client IP and namespace rules to an HTTP ping
+// before its body is read
+func (r *gleanReceiver) throttleRequest(ctx context.Context, gleanRequest GleanPingRequest) *pingError {
+	if key, wait, ok := r.rateLimiter.allow(requestLimits(gleanRequest), time.Now()); !ok {
+		return r.newThrottledPingError(ctx, key, wait)
+	}
+	return nil
+}
+
+// throttleClientID applies the client_id rules to an HTTP ping once its
+// body is parsed. Buckets are held under the client's state key so deletion
+// requests purge them. A throttled client gets back the client IP and
+// namespace tokens of the ping, so it does not drain the budget it shares
+// with other clients.
+func (r *gleanReceiver) throttleClientID(ctx context.Context, gleanRequest GleanPingRequest, clientKey string) *pingError {
+	if key, wait, ok := r.rateLimiter.allow([]rateLimitValue{{rateLimitKeyClientID, clientKey}}, time.Now()); !ok {
+		r.rateLimiter.giveBack(requestLimits(gleanRequest))
+		return r.newThrottledPingError(ctx, key, wait)
+	}
+	return nil
+}
+
+// newThrottledPingError records a throttled ping and returns the 429 error
+// telling the client when to retry
+func (r *gleanReceiver) newThrottledPingError(ctx context.Context, key string, wait time.Duration) *pingError {
+	r.telemetry.recordThrottledPing(ctx, key)
+	r.logger.Debug("Throttling ping", zap.String("key", key), zap.Duration("retry_after", wait))
+	return &pingError{status: http.StatusTooManyRequests, message: "Too many pings", retryAfter: wait}
+}
@@ -0,0 +1,257 @@
+package gleanreceiver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+)
+
+func TestRateLimiterTokenBucket(t *testing.T) {
+	limiter := newRateLimiter(RateLimitConfig{
+		Rules:   []RateLimitRule{{Key: rateLimitKeyClientIP, Rate: 2, Burst: 3}},
+		MaxKeys: 10,
+	})
+	now := time.Now()
+
+	for range 3 {
+		_, _, ok := limiter.allow([]rateLimitValue{{rateLimitKeyClientIP, "10.0.0.1"}}, now)
+		require.True(t, ok)
+	}
+	_, wait, ok := limiter.allow([]rateLimitValue{{rateLimitKeyClientIP, "10.0.0.1"}}, now)
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	// Other values and keys have their own buckets
+	_, _, ok = limiter.allow([]rateLimitValue{{rateLimitKeyClientIP, "10.0.0.2"}}, now)
+	assert.True(t, ok)
+	_, _, ok = limiter.allow([]rateLimitValue{{rateLimitKeyNamespace, "test-app"}}, now)
+	assert.True(t, ok)
+
+	// The bucket refills at rate
+	_, _, ok = limiter.allow([]rateLimitValue{{rateLimitKeyClientIP, "10.0.0.1"}}, now.Add(500*time.Millisecond))
+	assert.True(t, ok)
+	_, _, ok = limiter.allow([]rateLimitValue{{rateLimitKeyClientIP, "10.0.0.1"}}, now.Add(500*time.Millisecond))
+	assert.False(t, ok)
+}
+
+func TestRateLimiterDefaultBurst(t *testing.T) {
+	limiter := newRateLimiter(RateLimitConfig{
+		Rules:   []RateLimitRule{{Key: rateLimitKeyNamespace, Rate: 0.5}},
+		MaxKeys: 10,
+	})
+	now := time.Now()
+
+	_, _, ok := limiter.allow([]rateLimitValue{{rateLimitKeyNamespace, "test-app"}}, now)
+	assert.True(t, ok)
+	_, wait, ok := limiter.allow([]rateLimitValue{{rateLimitKeyNamespace, "test-app"}}, now)
+	assert.False(t, ok)
+	assert.Equal(t, 2*time.Second, wait)
+}
+
+func TestRateLimiterRefusalDoesNotDrainOtherRules(t *testing.T) {
+	limiter := newRateLimiter(RateLimitConfig{
+		Rules: []RateLimitRule{
+			{Key: rateLimitKeyNamespace, Rate: 1, Burst: 5},
+			{Key: rateLimitKeyNamespace, Rate: 1, Burst: 1},
+		},
+		MaxKeys: 10,
+	})
+	now := time.Now()
+
+	_, _, ok := limiter.allow([]rateLimitValue{{rateLimitKeyNamespace, "test-app"}}, now)
+	require.True(t, ok)
+	for range 3 {
+		_, _, ok = limiter.allow([]rateLimitValue{{rateLimitKeyNamespace, "test-app"}}, now)
+		assert.False(t, ok)
+	}
+
+	// Refused requests took no token from the first rule
+	bucket := limiter.rules[0].buckets["test-app"].Value.(*tokenBucket)
+	assert.Equal(t, float64(4), bucket.tokens)
+}
+
+func TestRateLimiterRefusalDoesNotDrainOtherKeys(t *testing.T) {
+	limiter := newRateLimiter(RateLimitConfig{
+		Rules: []RateLimitRule{
+			{Key: rateLimitKeyClientIP, Rate: 1, Burst: 5},
+			{Key: rateLimitKeyNamespace, Rate: 1, Burst: 1},
+		},
+		MaxKeys: 10,
+	})
+	limits := []rateLimitValue{{rateLimitKeyClientIP, "10.0.0.1"}, {rateLimitKeyNamespace, "test-app"}}
+	now := time.Now()
+
+	_, _, ok := limiter.allow(limits, now)
+	require.True(t, ok)
+	key, _, ok := limiter.allow(limits, now)
+	assert.False(t, ok)
+	assert.Equal(t, rateLimitKeyNamespace, key)
+
+	// The refused request took no token from the client IP rule
+	bucket := limiter.rules[0].buckets["10.0.0.1"].Value.(*tokenBucket)
+	assert.Equal(t, float64(4), bucket.tokens)
+
+	// Tokens given back return to the buckets, up to the burst
+	limiter.giveBack(limits)
+	limiter.giveBack(limits)
+	assert.Equal(t, float64(5), bucket.tokens)
+	_, _, ok = limiter.allow(limits, now)
+	assert.True(t, ok)
+}
+
+func TestRateLimiterEvictsLeastRecentlyUsed(t *testing.T) {
+	limiter := newRateLimiter(RateLimitConfig{
+		Rules:   []RateLimitRule{{Key: rateLimitKeyClientID, Rate: 1, Burst: 1}},
+		MaxKeys: 2,
+	})
+	rule := limiter.rules[0]
+	now := time.Now()
+
+	limiter.allow([]rateLimitValue{{rateLimitKeyClientID, "a"}}, now)
+	limiter.allow([]rateLimitValue{{rateLimitKeyClientID, "b"}}, now)
+	limiter.allow([]rateLimitValue{{rateLimitKeyClientID, "a"}}, now)
+	limiter.allow([]rateLimitValue{{rateLimitKeyClientID, "c"}}, now)
+
+	assert.Len(t, rule.buckets, 2)
+	assert.Contains(t, rule.buckets, "a")
+	assert.NotContains(t, rule.buckets, "b")
+
+	limiter.purge(rateLimitKeyClientID, "a")
+	assert.NotContains(t, rule.buckets, "a")
+	assert.Equal(t, 1, rule.recent.Len())
+}
+
+func TestRateLimiterClientIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.RemoteAddr = "192.0.2.1:54321"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+
+	limiter := &rateLimiter{trustedProxies: 1}
+	assert.Equal(t, "192.0.2.1", limiter.clientIP(req))
+
+	// The entry appended by the only proxy is the rightmost one
+	limiter.clientIPHeader = "X-Forwarded-For"
+	assert.Equal(t, "10.0.0.1", limiter.clientIP(req))
+
+	// Behind two proxies, the client is the second entry from the right
+	limiter.trustedProxies = 2
+	assert.Equal(t, "203.0.113.7", limiter.clientIP(req))
+
+	// Proxies appending a header line of their own are counted the same
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	req.Header.Add("X-Forwarded-For", "10.0.0.1")
+	assert.Equal(t, "203.0.113.7", limiter.clientIP(req))
+
+	// Fewer entries than proxies means the request bypassed them
+	req.Header.Set("X-Forwarded-For", "10.0.0.1")
+	assert.Equal(t, "192.0.2.1", limiter.clientIP(req))
+
+	req.Header.Del("X-Forwarded-For")
+	assert.Equal(t, "192.0.2.1", limiter.clientIP(req))
+}
+
+func TestRateLimiterClientIPSpoofed(t *testing.T) {
+	limiter := newRateLimiter(RateLimitConfig{
+		Rules:          []RateLimitRule{{Key: rateLimitKeyClientIP, Rate: 1, Burst: 1}},
+		ClientIPHeader: "X-Forwarded-For",
+		TrustedProxies: 1,
+		MaxKeys:        10,
+	})
+	now := time.Now()
+
+	// A flooder sending a new leftmost entry each time stays in the bucket
+	// of the address the proxy saw
+	for i, spoofed := range []string{"198.51.100.1", "198.51.100.2", "198.51.100.3"} {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("X-Forwarded-For", spoofed+", 203.0.113.7")
+		_, _, ok := limiter.allow([]rateLimitValue{{rateLimitKeyClientIP, limiter.clientIP(req)}}, now)
+		assert.Equal(t, i == 0, ok)
+	}
+	assert.Equal(t, 1, limiter.rules[0].recent.Len())
+}
+
+func TestReceiverRateLimit(t *testing.T) {
+	cfg := &Config{
+		Path:      "/test",
+		Allowlist: AllowlistConfig{Namespaces: []string{"test-app"}},
+		RateLimit: RateLimitConfig{
+			Rules: []RateLimitRule{
+				{Key: rateLimitKeyClientID, Rate: 0.01, Burst: 1},
+				{Key: rateLimitKeyNamespace, Rate: 0.01, Burst: 3},
+			},
+			MaxKeys: 100,
+		},
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19919"
+
+	metricsSink := new(consumertest.MetricsSink)
+	logsSink := new(consumertest.LogsSink)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		metricsSink,
+		logsSink,
+		nil,
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	send := func(namespace, clientID, documentType string) *http.Response {
+		body, err := json.Marshal(GleanPing{
+			ClientInfo: ClientInfo{ClientID: clientID},
+			PingInfo:   PingInfo{StartTime: time.Now(), EndTime: time.Now(), PingType: documentType},
+			Metrics: map[string]any{
+				"counter": map[string]any{"test_counter": float64(1)},
+			},
+		})
+		require.NoError(t, err)
+		resp, err := http.Post(
+			"http://localhost:19919/test/"+namespace+"/"+documentType+"/1/doc-1",
+			"application/json",
+			bytes.NewBuffer(body),
+		)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	assert.Equal(t, http.StatusOK, send("test-app", "client-1", "metrics").StatusCode)
+
+	// The client is throttled and told when to retry
+	for range 3 {
+		resp := send("test-app", "client-1", "metrics")
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, "100", resp.Header.Get("Retry-After"))
+	}
+
+	// Deletion requests are never throttled and purge the client's bucket
+	assert.Equal(t, http.StatusOK, send("test-app", "client-1", "deletion-request").StatusCode)
+	assert.Equal(t, http.StatusOK, send("test-app", "client-1", "metrics").StatusCode)
+
+	// Pings of the throttled client did not drain the namespace budget
+	// shared by every client
+	assert.Equal(t, http.StatusOK, send("test-app", "client-2", "metrics").StatusCode)
+	assert.Equal(t, http.StatusTooManyRequests, send("test-app", "client-3", "metrics").StatusCode)
+
+	// Pings outside the allowlist are refused before they are throttled
+	assert.Equal(t, http.StatusForbidden, send("other-app", "client-4", "metrics").StatusCode)
+	assert.NotContains(t, receiver.rateLimiter.rules[1].buckets, "other-app")
+
+	assert.Len(t, metricsSink.AllMetrics(), 3)
+}
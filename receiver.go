@@ -5,27 +5,58 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 	"sync"
+	"time"
 
+	"github.com/mozilla/gleanotelreceiver/gleanpb"
 	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configgrpc"
 	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/ptrace"
 	"go.opentelemetry.io/collector/receiver"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
-// gleanReceiver implements the receiver.Metrics and receiver.Logs interfaces
+// gleanReceiver implements the receiver.Metrics, receiver.Logs and receiver.Traces interfaces
 type gleanReceiver struct {
 	cfg             *Config
 	logger          *zap.Logger
+	settings        component.TelemetrySettings
 	metricsConsumer consumer.Metrics
 	logsConsumer    consumer.Logs
+	tracesConsumer  consumer.Traces
 	server          *http.Server
+	debugServer     *http.Server
+	grpcServer      *grpc.Server
 	host            component.Host
 	startOnce       sync.Once
 	shutdownOnce    sync.Once
 	forwarder       *gleanPingForwarder
+	deletionForward *gleanPingForwarder
+	sessions        *sessionStitcher
+	pseudonymizer   *identifierPseudonymizer
+	clientKeys      *clientKeyer
+	redactor        *redactor
+	telemetry       *receiverTelemetry
+	converter       converterSettings
+	validator       *pingValidator
+	allowlist       *pingAllowlist
+	sourceTags      *sourceTagRouter
+	debug           *debugView
+	pendingPings    *pendingPingsReader
+	admission       *admissionController
+	rateLimiter     *rateLimiter
+	limits          *pingLimits
+
+	// stop is closed on shutdown to end the background goroutines
+	stop       chan struct{}
+	background sync.WaitGroup
 }
 
 // newGleanReceiver creates a new instance of gleanReceiver
@@ -34,9 +65,10 @@ func newGleanReceiver(
 	set receiver.Settings,
 	metricsConsumer consumer.Metrics,
 	logsConsumer consumer.Logs,
+	tracesConsumer consumer.Traces,
 ) (*gleanReceiver, error) {
-	if metricsConsumer == nil && logsConsumer == nil {
-		return nil, errors.New("at least one consumer (metrics or logs) must be provided")
+	if metricsConsumer == nil && logsConsumer == nil && tracesConsumer == nil {
+		return nil, errors.New("at least one consumer (metrics, logs or traces) must be provided")
 	}
 	var forwarder *gleanPingForwarder
 	var err error
@@ -46,12 +78,76 @@ func newGleanReceiver(
 			set.Logger.Error("Error creating ping forwarder: %s", zap.Error(err))
 		}
 	}
+	var deletionForward *gleanPingForwarder
+	if cfg.DeletionRequest.ForwardURL != "" {
+		deletionForward, err = newDeletionRequestForwarder(cfg, set)
+		if err != nil {
+			set.Logger.Error("Error creating deletion-request forwarder", zap.Error(err))
+		}
+	}
+	pseudonymizer, err := newIdentifierPseudonymizer(cfg.Privacy)
+	if err != nil {
+		return nil, err
+	}
+	redactor, err := newRedactor(cfg.Redaction)
+	if err != nil {
+		return nil, err
+	}
+	telemetry, err := newReceiverTelemetry(set)
+	if err != nil {
+		return nil, err
+	}
+	converter, err := newConverterSettings(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var validator *pingValidator
+	if cfg.SchemaValidation.Enabled {
+		validator, err = newPingValidator(cfg.SchemaValidation.SchemaPath)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.SchemaValidation.SchemaPath == "" {
+			set.Logger.Warn("Schema validation only checks the ping structure, set schema_path to the Glean schema from mozilla-pipeline-schemas for full validation")
+		}
+	}
+	allowlist, err := newPingAllowlist(cfg.Allowlist)
+	if err != nil {
+		return nil, err
+	}
+	sourceTags, err := newSourceTagRouter(cfg.SourceTags)
+	if err != nil {
+		return nil, err
+	}
+	var sessions *sessionStitcher
+	if cfg.Sessions.Enabled {
+		sessions = newSessionStitcher(cfg.Sessions, converter.attributes)
+	}
+	clientKeys := newClientKeyer()
 	return &gleanReceiver{
 		cfg:             cfg,
 		logger:          set.Logger,
+		settings:        set.TelemetrySettings,
 		metricsConsumer: metricsConsumer,
 		logsConsumer:    logsConsumer,
+		tracesConsumer:  tracesConsumer,
 		forwarder:       forwarder,
+		deletionForward: deletionForward,
+		sessions:        sessions,
+		pseudonymizer:   pseudonymizer,
+		clientKeys:      clientKeys,
+		redactor:        redactor,
+		telemetry:       telemetry,
+		converter:       converter,
+		validator:       validator,
+		allowlist:       allowlist,
+		sourceTags:      sourceTags,
+		debug:           newDebugView(cfg.Debug, clientKeys),
+		pendingPings:    newPendingPingsReader(cfg.PendingPings),
+		admission:       newAdmissionController(cfg.Admission),
+		rateLimiter:     newRateLimiter(cfg.RateLimit),
+		limits:          newPingLimits(cfg.Limits),
+		stop:            make(chan struct{}),
 	}, nil
 }
 
@@ -61,8 +157,22 @@ func (r *gleanReceiver) Start(ctx context.Context, host component.Host) error {
 	r.startOnce.Do(func() {
 		r.host = host
 
+		if id := r.cfg.Admission.MemoryLimiter; id != nil {
+			if startErr = r.admission.resolveMemoryLimiter(host, *id); startErr != nil {
+				return
+			}
+		}
+
 		mux := http.NewServeMux()
-		mux.HandleFunc(r.cfg.GetPath(), r.handleGleanPing)
+		for _, route := range r.cfg.routes() {
+			mux.HandleFunc(route.Path, r.handleGleanPing(route))
+		}
+
+		listeners, err := listenAll(ctx, r.cfg.listenAddrs())
+		if err != nil {
+			startErr = err
+			return
+		}
 
 		r.server = &http.Server{
 			Addr:              r.cfg.NetAddr.Endpoint,
@@ -70,57 +180,490 @@ func (r *gleanReceiver) Start(ctx context.Context, host component.Host) error {
 			ReadHeaderTimeout: r.cfg.ReadHeaderTimeout,
 		}
 
-		r.logger.Info("Starting Glean receiver",
-			zap.String("endpoint", r.cfg.NetAddr.Endpoint),
-			zap.String("path", r.cfg.GetPath()))
+		// Every listener serves the same handlers
+		for _, listener := range listeners {
+			r.logger.Info("Starting Glean receiver",
+				zap.String("endpoint", listener.Addr().String()),
+				zap.String("transport", listener.Addr().Network()),
+				zap.String("path", r.cfg.GetPath()),
+				zap.Int("routes", len(r.cfg.Routes)))
 
-		go func() {
-			if err := r.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-				r.logger.Error("Error starting HTTP server", zap.Error(err))
+			go func() {
+				if err := r.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+					r.logger.Error("Error starting HTTP server", zap.Error(err))
+				}
+			}()
+		}
+
+		if grpcCfg := r.cfg.GRPC.Get(); grpcCfg != nil {
+			if startErr = r.startGRPC(ctx, host, grpcCfg); startErr != nil {
+				return
+			}
+		}
+
+		if r.debug != nil {
+			if startErr = r.startDebug(ctx); startErr != nil {
+				return
+			}
+		}
+
+		if r.sessions != nil {
+			if r.tracesConsumer == nil {
+				// Sessions are only stitched for a traces pipeline
+				r.logger.Warn("Session reconstruction is enabled but the receiver is not in a traces pipeline, no sessions will be stitched")
+			} else {
+				r.background.Add(1)
+				go r.expireSessions()
+			}
+		}
+
+		if r.pendingPings != nil {
+			r.logger.Info("Reading Glean pending_pings directories",
+				zap.Strings("directories", r.cfg.PendingPings.Directories))
+			r.background.Add(1)
+			go r.pollPendingPings()
+		}
+
+		if len(r.cfg.Replay.Paths) > 0 {
+			r.logger.Info("Replaying Glean ping archives",
+				zap.Strings("paths", r.cfg.Replay.Paths),
+				zap.Float64("rate", r.cfg.Replay.Rate),
+				zap.Duration("time_shift", r.cfg.Replay.TimeShift))
+			r.background.Add(1)
+			go r.replayArchives()
+		}
+
+		// The Kafka client is created last so no later error leaves it open;
+		// consumeKafka closes it on shutdown
+		if len(r.cfg.Kafka.Brokers) > 0 {
+			kafkaClient, err := newKafkaClient(r.cfg.Kafka)
+			if err != nil {
+				startErr = err
+				return
 			}
-		}()
+			r.logger.Info("Consuming Glean pings from Kafka",
+				zap.Strings("brokers", r.cfg.Kafka.Brokers),
+				zap.Strings("topics", r.cfg.Kafka.Topics),
+				zap.String("group_id", r.cfg.Kafka.GroupID))
+			r.background.Add(1)
+			go r.consumeKafka(kafkaClient)
+		}
 	})
 
 	return startErr
 }
 
+// startGRPC starts serving the gRPC ingestion API
+func (r *gleanReceiver) startGRPC(ctx context.Context, host component.Host, grpcCfg *configgrpc.ServerConfig) error {
+	var err error
+	r.grpcServer, err = grpcCfg.ToServer(ctx, host.GetExtensions(), r.settings)
+	if err != nil {
+		return err
+	}
+	gleanpb.RegisterPingIngestServer(r.grpcServer, &pingIngestServer{receiver: r})
+
+	listener, err := grpcCfg.NetAddr.Listen(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.logger.Info("Starting Glean gRPC ingestion API", zap.String("endpoint", grpcCfg.NetAddr.Endpoint))
+
+	go func() {
+		if err := r.grpcServer.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			r.logger.Error("Error starting gRPC server", zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// startDebug serves the debug view on its own endpoint so captured pings are
+// never reachable through the ingestion endpoint
+func (r *gleanReceiver) startDebug(ctx context.Context) error {
+	mux := http.NewServeMux()
+	r.debug.register(mux)
+
+	listener, err := (&net.ListenConfig{}).Listen(ctx, "tcp", r.cfg.Debug.Endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", r.cfg.Debug.Endpoint, err)
+	}
+	r.debugServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: r.cfg.ReadHeaderTimeout,
+	}
+
+	r.logger.Info("Starting Glean debug view",
+		zap.String("endpoint", listener.Addr().String()),
+		zap.String("path", r.cfg.Debug.Path))
+
+	go func() {
+		if err := r.debugServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			r.logger.Error("Error starting debug HTTP server", zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// expireSessions periodically closes idle sessions until the receiver shuts down
+func (r *gleanReceiver) expireSessions() {
+	defer r.background.Done()
+
+	interval := min(r.cfg.Sessions.IdleTimeout/2, time.Minute)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.consumeSessionTraces(context.Background(), r.sessions.expire())
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// consumeSessionTraces sends closed session root spans downstream
+func (r *gleanReceiver) consumeSessionTraces(ctx context.Context, traces ptrace.Traces) {
+	if r.tracesConsumer == nil || traces.SpanCount() == 0 {
+		return
+	}
+	if err := r.tracesConsumer.ConsumeTraces(ctx, traces); err != nil {
+		r.logger.Error("Failed to consume session traces", zap.Error(err))
+	}
+}
+
 // Shutdown stops the HTTP server
 func (r *gleanReceiver) Shutdown(ctx context.Context) error {
 	var shutdownErr error
 	r.shutdownOnce.Do(func() {
+		if r.debug != nil {
+			// End live debug streams so the server can drain its connections
+			r.debug.close()
+		}
 		if r.server != nil {
 			r.logger.Info("Shutting down Glean receiver")
 			shutdownErr = r.server.Shutdown(ctx)
 		}
+		if r.debugServer != nil {
+			shutdownErr = errors.Join(shutdownErr, r.debugServer.Shutdown(ctx))
+		}
+		if r.grpcServer != nil {
+			// Cancel in-flight calls that do not finish before ctx is done
+			stopped := make(chan struct{})
+			go func() {
+				r.grpcServer.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+			case <-ctx.Done():
+				r.grpcServer.Stop()
+				<-stopped
+				shutdownErr = errors.Join(shutdownErr, ctx.Err())
+			}
+		}
+		close(r.stop)
+		r.background.Wait()
+		if r.sessions != nil {
+			// Emit root spans for sessions that are still open
+			r.consumeSessionTraces(ctx, r.sessions.flush())
+		}
 	})
 	return shutdownErr
 }
 
-// handleGleanPing processes incoming Glean ping requests
-func (r *gleanReceiver) handleGleanPing(w http.ResponseWriter, req *http.Request) {
-	if req.Method != http.MethodPost {
-		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
-		return
+// handleGleanPing returns the handler processing incoming Glean ping
+// requests on a route
+func (r *gleanReceiver) handleGleanPing(route RouteConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		defer req.Body.Close()
+
+		// Refuse declared oversized pings before reading them, others fail
+		// once the body crosses the limit
+		maxBodySize := r.cfg.maxRequestBodySize()
+		if req.ContentLength > maxBodySize {
+			r.telemetry.recordLimitedPing(req.Context(), limitBodyBytes, limitActionReject)
+			writePingError(w, errPingTooLarge)
+			return
+		}
+		req.Body = http.MaxBytesReader(w, req.Body, maxBodySize)
+
+		gleanRequest := route.pingRequest(req)
+		// The body is handed on decompressed
+		gleanRequest.Headers.Del("Content-Encoding")
+		gleanRequest.RateLimited = r.rateLimited(gleanRequest)
+		if gleanRequest.RateLimited {
+			gleanRequest.ClientIP = r.rateLimiter.clientIP(req)
+		}
+
+		// Refuse pings before buffering them while the receiver is busy
+		var admitted *admittedRequest
+		if r.admission != nil {
+			var reason string
+			admitted, reason = r.admission.admit(req)
+			if reason != "" {
+				r.telemetry.recordRefusedPing(req.Context(), reason)
+				r.logger.Debug("Refusing ping by admission control", zap.String("reason", reason))
+				writePingError(w, r.newUnavailablePingError("Receiver is busy"))
+				return
+			}
+			defer admitted.release()
+		}
+
+		err := r.ingestPing(req.Context(), gleanRequest, func() ([]byte, error) {
+			return r.readPingBody(req, admitted)
+		})
+
+		var pingErr *pingError
+		switch {
+		case errors.As(err, &pingErr):
+			writePingError(w, pingErr)
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, "OK")
+		}
+	}
+}
+
+// readPingBody reads and decompresses the body of an HTTP ping, refusing it
+// when it crosses a size limit or the in-flight bytes
+func (r *gleanReceiver) readPingBody(req *http.Request, admitted *admittedRequest) ([]byte, error) {
+	body, err := readRequestBody(req, r.cfg.maxDecompressedBytes(), admitted)
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		r.telemetry.recordLimitedPing(req.Context(), limitBodyBytes, limitActionReject)
+		return nil, errPingTooLarge
+	case errors.Is(err, errDecompressedTooLarge):
+		r.telemetry.recordLimitedPing(req.Context(), limitDecompressedBytes, limitActionReject)
+		return nil, err
+	case errors.Is(err, errInFlightBytesExceeded):
+		r.telemetry.recordRefusedPing(req.Context(), refusedInFlightBytes)
+		return nil, r.newUnavailablePingError("Receiver is busy")
+	}
+	return body, err
+}
+
+// writePingError answers a request with the status of a ping error
+func writePingError(w http.ResponseWriter, pingErr *pingError) {
+	if pingErr.retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(pingErr.retryAfter.Seconds()))))
 	}
+	http.Error(w, pingErr.message, pingErr.status)
+}
+
+// defaultMaxRequestBodySize is the body limit when max_request_body_size is
+// not set, the confighttp default
+const defaultMaxRequestBodySize = 20 * 1024 * 1024
+
+// errPingTooLarge is returned for pings over max_request_body_size
+var errPingTooLarge = newPingError(http.StatusRequestEntityTooLarge, "Ping exceeds the maximum request body size")
+
+// pingError is returned when a ping is refused or cannot be processed. status
+// is the HTTP status the ping maps to for any transport. As with the Mozilla
+// ingestion edge, Glean SDKs retry pings answered with a 5xx status and drop
+// those answered with a 4xx status.
+type pingError struct {
+	status     int
+	message    string
+	retryAfter time.Duration
+}
 
-	gleanRequest := GleanPingRequest{
-		Namespace:       req.PathValue("namespace"),
-		DocumentType:    req.PathValue("document_type"),
-		DocumentVersion: req.PathValue("document_version"),
-		DocumentID:      req.PathValue("document_id"),
-		Headers:         req.Header.Clone(),
+func newPingError(status int, message string) *pingError {
+	return &pingError{status: status, message: message}
+}
+
+// newConsumerPingError maps a pipeline error. Permanent errors will fail
+// again and are not retried, any other error is treated as backpressure the
+// client retries after retry_after.
+func (r *gleanReceiver) newConsumerPingError(err error, message string) *pingError {
+	if consumererror.IsPermanent(err) {
+		return newPingError(http.StatusBadRequest, message)
 	}
+	return r.newUnavailablePingError(message)
+}
+
+// newUnavailablePingError is returned when the ping should be retried after
+// retry_after
+func (r *gleanReceiver) newUnavailablePingError(message string) *pingError {
+	return &pingError{status: http.StatusServiceUnavailable, message: message, retryAfter: r.cfg.RetryAfter}
+}
+
+func (e *pingError) Error() string {
+	return e.message
+}
 
-	body, err := io.ReadAll(req.Body)
+// pingStatus returns the HTTP status for the result of ingestPing
+func pingStatus(err error) int {
+	var pingErr *pingError
+	switch {
+	case errors.As(err, &pingErr):
+		return pingErr.status
+	case err != nil:
+		return http.StatusInternalServerError
+	default:
+		return http.StatusOK
+	}
+}
+
+// Steps of a ping that are done at most once across retries
+const (
+	deliveryForward         = "forward"
+	deliveryDeletionForward = "deletion_forward"
+	deliveryMetrics         = "metrics"
+	deliveryEventLogs       = "event_logs"
+	deliveryTraces          = "traces"
+	deliverySessions        = "sessions"
+)
+
+// pingDelivery remembers the steps already done for a ping the receiver
+// retries itself, such as a Kafka record or a pending ping file, so that a
+// retry neither forwards the ping again nor re-sends the signals the
+// pipeline accepted. A nil pingDelivery does every step.
+type pingDelivery struct {
+	done map[string]bool
+
+	// sessionTraces holds the session spans closed by the ping until the
+	// pipeline accepts them, as the ping is only stitched once
+	sessionTraces *ptrace.Traces
+}
+
+// newPingDelivery creates a new instance of pingDelivery
+func newPingDelivery() *pingDelivery {
+	return &pingDelivery{done: make(map[string]bool)}
+}
+
+// has reports whether step was done by an earlier attempt
+func (d *pingDelivery) has(step string) bool {
+	return d != nil && d.done[step]
+}
+
+// mark records that step is done
+func (d *pingDelivery) mark(step string) {
+	if d != nil {
+		d.done[step] = true
+	}
+}
+
+// stitch returns the session spans of the ping, stitching it on the first
+// attempt only
+func (d *pingDelivery) stitch(stitch func() ptrace.Traces) ptrace.Traces {
+	if d == nil {
+		return stitch()
+	}
+	if d.sessionTraces == nil {
+		traces := stitch()
+		d.sessionTraces = &traces
+	}
+	return *d.sessionTraces
+}
+
+// ingestPing runs a ping through the receiver, whichever way it arrived.
+// readBody is only called once the ping passed the checks that do not need
+// its payload.
+func (r *gleanReceiver) ingestPing(ctx context.Context, gleanRequest GleanPingRequest, readBody func() ([]byte, error)) (err error) {
+	// Capture pings tagged for the debug view, or every ping while someone
+	// tails the receiver, with the outcome they got
+	if r.debug != nil {
+		if debugID := gleanRequest.Headers.Get(debugIDHeader); debugID != "" || r.debug.tailing() {
+			capture := newDebugPing(debugID, gleanRequest)
+			defer func() {
+				capture.Status = pingStatus(err)
+				r.debug.record(capture)
+			}()
+			return r.processPing(ctx, gleanRequest, readBody, capture)
+		}
+	}
+	return r.processPing(ctx, gleanRequest, readBody, nil)
+}
+
+// processPing refuses, forwards, validates and converts a single ping
+func (r *gleanReceiver) processPing(ctx context.Context, gleanRequest GleanPingRequest, readBody func() ([]byte, error), capture *debugPing) error {
+	// Deletion-request pings are exempt from source tag drops and only
+	// checked against the allowed namespaces: a refused or dropped deletion
+	// request would never be purged
+	deletion := gleanRequest.DocumentType == deletionRequestDocumentType
+
+	// Refuse pings outside the allowlist before reading their body
+	if r.allowlist != nil {
+		if reason := r.allowlist.check(gleanRequest); reason != "" {
+			r.telemetry.recordRefusedPing(ctx, reason)
+			capture.warnf("ping refused by the allowlist: %s", reason)
+			r.logger.Debug("Refusing ping outside the allowlist",
+				zap.String("reason", reason),
+				zap.String("namespace", gleanRequest.Namespace),
+				zap.String("document_type", gleanRequest.DocumentType),
+				zap.String("document_version", gleanRequest.DocumentVersion))
+			return newPingError(http.StatusForbidden, "Ping not allowed: "+reason)
+		}
+	}
+
+	// Throttle HTTP pings by client IP and namespace once they are allowed,
+	// so refused pings do not take tokens or create buckets
+	if gleanRequest.RateLimited {
+		if pingErr := r.throttleRequest(ctx, gleanRequest); pingErr != nil {
+			capture.warnf("ping throttled: %s", pingErr.message)
+			return pingErr
+		}
+	}
+
+	// Drop, tag or route test and automation traffic by its source tags
+	if r.sourceTags != nil {
+		decision := r.sourceTags.decide(gleanRequest.Headers)
+		if decision.drop && !deletion {
+			r.telemetry.recordRefusedPing(ctx, refusedSourceTag)
+			r.logger.Debug("Dropping ping by source tag",
+				zap.Strings("source_tags", parseSourceTags(gleanRequest.Headers)),
+				zap.String("document_id", gleanRequest.DocumentID))
+			capture.warnf("ping dropped by source tag rule")
+			// Acknowledge the ping so the SDK does not retry it
+			return nil
+		}
+		gleanRequest.SourceTags = decision.tags
+		gleanRequest.Route = decision.route
+	}
+
+	body, err := readBody()
 	if err != nil {
-		r.logger.Error("Failed to read request body", zap.Error(err))
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
-		return
+		// The reader refused the ping, for example by admission control
+		var pingErr *pingError
+		if errors.As(err, &pingErr) {
+			capture.warnf("ping refused while reading: %s", pingErr.message)
+			return pingErr
+		}
+		r.logger.Error("Failed to read ping body", zap.Error(err))
+		capture.warnf("failed to read request body: %v", err)
+		return newPingError(http.StatusBadRequest, "Failed to read request body")
+	}
+
+	// Parse the ping once, invalid JSON is refused after validation
+	var ping GleanPing
+	parseErr := json.Unmarshal(body, &ping)
+	clientKey := r.clientKeys.key(ping.ClientInfo.ClientID)
+	// Sessions are held under the raw identifiers, before privacy settings
+	// change or drop them
+	var sessionKey string
+	if r.sessions != nil {
+		sessionKey = r.clientKeys.sessionKey(clientKey, gleanRequest.Namespace, ping.ClientInfo.SessionID)
 	}
-	defer req.Body.Close()
 
-	// Forward raw body to downstream if configured
-	if r.forwarder != nil {
+	// Throttle clients once their client_id is known
+	if parseErr == nil && gleanRequest.RateLimited && r.rateLimiter.limits(rateLimitKeyClientID) {
+		if pingErr := r.throttleClientID(ctx, gleanRequest, clientKey); pingErr != nil {
+			capture.warnf("ping throttled: %s", pingErr.message)
+			return pingErr
+		}
+	}
+
+	// Forward raw body to downstream if configured. Replayed pings were
+	// already received upstream when they were archived.
+	if r.forwarder != nil && !gleanRequest.Replayed && !gleanRequest.Delivery.has(deliveryForward) {
 		r.logger.Info("Forwarding glean ping")
 		if err := r.forwarder.forwardRawPing(context.Background(), gleanRequest, body); err != nil {
 			r.logger.Error("Failed to forward ping to downstream",
@@ -128,51 +671,262 @@ func (r *gleanReceiver) handleGleanPing(w http.ResponseWriter, req *http.Request
 				zap.String("downstream_url", r.cfg.ForwardURL))
 			// Continue processing even if forward fails
 		}
+		gleanRequest.Delivery.mark(deliveryForward)
 	}
 
-	var ping GleanPing
+	// Expose the request metadata to downstream processors
+	ctx = withRequestMetadata(ctx, gleanRequest, r.cfg.RequestHeaders)
 
-	if err := json.Unmarshal(body, &ping); err != nil {
-		r.logger.Error("Failed to parse Glean ping", zap.Error(err))
-		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
-		return
+	// Deletion-request pings skip validation, limits and the registry and
+	// are not converted. A refused deletion request is dropped by the client,
+	// so the deletion would be lost.
+	if deletion {
+		if parseErr != nil {
+			r.logger.Error("Failed to parse deletion-request ping", zap.Error(parseErr))
+			capture.warnf("invalid JSON: %v", parseErr)
+			return newPingError(http.StatusBadRequest, "Invalid JSON format")
+		}
+		if clientKey == "" {
+			r.logger.Error("Deletion-request ping without client_id, no client state can be purged",
+				zap.String("namespace", gleanRequest.Namespace),
+				zap.String("document_id", gleanRequest.DocumentID))
+			capture.warnf("deletion request without client_id")
+			return newPingError(http.StatusBadRequest, "Deletion request without client_id")
+		}
+		// The debug view only keeps the outcome, the client is being deleted
+		ping.Request = gleanRequest
+		r.applyPrivacy(ctx, &ping, capture)
+		if err := r.handleDeletionRequest(ctx, &ping, body, clientKey); err != nil {
+			r.logger.Error("Failed to process deletion-request ping", zap.Error(err))
+			capture.warnf("failed to process deletion request: %v", err)
+			return r.newConsumerPingError(err, "Failed to process deletion request")
+		}
+		return nil
+	}
+
+	// Validate the raw payload against the Glean ping schema if enabled
+	if r.validator != nil {
+		if violations := r.validator.validate(body); len(violations) > 0 {
+			r.telemetry.recordValidationFailures(ctx, violations)
+			for _, violation := range violations {
+				capture.warnf("schema violation at %s: %s", violation.Path, violation.Message)
+			}
+			r.logger.Debug("Glean ping failed schema validation",
+				zap.String("document_id", gleanRequest.DocumentID),
+				zap.Int("violations", len(violations)))
+
+			if r.cfg.SchemaValidation.OnFailure != validationFailureLog {
+				return newPingError(http.StatusBadRequest, "Ping does not match the Glean schema")
+			}
+			if err := r.consumeValidationErrorLog(ctx, gleanRequest, violations); err != nil {
+				r.logger.Error("Failed to consume validation error log", zap.Error(err))
+				return r.newConsumerPingError(err, "Failed to process validation error")
+			}
+			return nil
+		}
+	}
+
+	if parseErr != nil {
+		r.logger.Error("Failed to parse Glean ping", zap.Error(parseErr))
+		capture.warnf("invalid JSON: %v", parseErr)
+		return newPingError(http.StatusBadRequest, "Invalid JSON format")
 	}
 
 	// set the pings request parameters
 	ping.Request = gleanRequest
 
+	// Enforce the event, metric, label and string limits before conversion
+	if r.limits != nil {
+		if exceeded := r.limits.apply(&ping); len(exceeded) > 0 {
+			for _, limit := range exceeded {
+				r.telemetry.recordLimitedPing(ctx, limit, r.limits.action)
+				capture.warnf("ping exceeds the %s limit (%s)", limit, r.limits.action)
+			}
+			if r.limits.action == limitActionReject {
+				r.logger.Debug("Rejecting ping over a payload limit",
+					zap.Strings("limits", exceeded),
+					zap.String("document_id", gleanRequest.DocumentID))
+				return newPingError(http.StatusRequestEntityTooLarge, "Ping exceeds the "+exceeded[0]+" limit")
+			}
+		}
+	}
+
+	// Move replayed pings in time, events follow as they are relative to start_time
+	if gleanRequest.TimeShift != 0 {
+		ping.PingInfo.shift(gleanRequest.TimeShift)
+	}
+	r.applyPrivacy(ctx, &ping, capture)
+	capture.setPing(&ping, clientKey)
+
+	// Reject pings that do not match the registry if configured to
+	if registry := r.converter.registry; registry != nil {
+		if capture != nil {
+			if !registry.hasPing(gleanRequest.DocumentType) {
+				capture.warnf("ping type %s is not declared in pings.yaml", gleanRequest.DocumentType)
+			}
+			for _, name := range registry.undeclaredMetrics(&ping) {
+				capture.warnf("metric %s is not declared in metrics.yaml", name)
+			}
+		}
+		if r.converter.undeclaredPings == undeclaredReject && !registry.hasPing(gleanRequest.DocumentType) {
+			r.logger.Warn("Rejecting undeclared ping type", zap.String("document_type", gleanRequest.DocumentType))
+			return newPingError(http.StatusBadRequest, "Undeclared ping type")
+		}
+		if r.converter.undeclaredMetrics == undeclaredReject {
+			if undeclared := registry.undeclaredMetrics(&ping); len(undeclared) > 0 {
+				r.logger.Warn("Rejecting ping with undeclared metrics", zap.Strings("metrics", undeclared))
+				return newPingError(http.StatusBadRequest, "Undeclared metrics")
+			}
+		}
+	}
+
+	// Conversion fails the same way every time, so conversion errors are
+	// refused with a 4xx status instead of being retried
+
 	// Convert to metrics if metrics consumer is available
-	if r.metricsConsumer != nil && ping.Metrics != nil {
-		metrics, err := convertToMetrics(&ping)
+	if r.metricsConsumer != nil && ping.Metrics != nil && !gleanRequest.Delivery.has(deliveryMetrics) {
+		metrics, err := convertToMetrics(&ping, r.converter)
 		if err != nil {
 			r.logger.Error("Failed to convert to metrics", zap.Error(err))
-			http.Error(w, "Failed to process metrics", http.StatusInternalServerError)
-			return
+			capture.warnf("failed to convert metrics: %v", err)
+			return newPingError(http.StatusBadRequest, "Failed to convert metrics")
 		}
+		capture.setMetrics(metrics)
 
-		if err := r.metricsConsumer.ConsumeMetrics(req.Context(), metrics); err != nil {
+		if err := r.metricsConsumer.ConsumeMetrics(ctx, metrics); err != nil {
 			r.logger.Error("Failed to consume metrics", zap.Error(err))
-			http.Error(w, "Failed to process metrics", http.StatusInternalServerError)
-			return
+			capture.warnf("failed to consume metrics: %v", err)
+			return r.newConsumerPingError(err, "Failed to process metrics")
 		}
+		gleanRequest.Delivery.mark(deliveryMetrics)
 	}
 
 	// Convert to event logs if logs consumer is available
-	if r.logsConsumer != nil && len(ping.Events) > 0 {
-		logs, err := convertToEventLogs(&ping)
+	if r.logsConsumer != nil && len(ping.Events) > 0 && !gleanRequest.Delivery.has(deliveryEventLogs) {
+		logs, err := convertToEventLogs(&ping, r.converter)
 		if err != nil {
 			r.logger.Error("Failed to convert to event logs", zap.Error(err))
-			http.Error(w, "Failed to process event logs", http.StatusInternalServerError)
-			return
+			capture.warnf("failed to convert events: %v", err)
+			return newPingError(http.StatusBadRequest, "Failed to convert event logs")
 		}
+		capture.setLogs(logs)
 
-		if err := r.logsConsumer.ConsumeLogs(req.Context(), logs); err != nil {
+		if err := r.logsConsumer.ConsumeLogs(ctx, logs); err != nil {
 			r.logger.Error("Failed to consume event logs", zap.Error(err))
-			http.Error(w, "Failed to process event logs", http.StatusInternalServerError)
-			return
+			capture.warnf("failed to consume event logs: %v", err)
+			return r.newConsumerPingError(err, "Failed to process event logs")
+		}
+		gleanRequest.Delivery.mark(deliveryEventLogs)
+	}
+
+	// Convert to ping lifecycle spans if traces consumer is available
+	if r.tracesConsumer != nil && !gleanRequest.Delivery.has(deliveryTraces) {
+		traces, err := convertToTraces(&ping, r.converter)
+		if err != nil {
+			r.logger.Error("Failed to convert to traces", zap.Error(err))
+			capture.warnf("failed to convert traces: %v", err)
+			return newPingError(http.StatusBadRequest, "Failed to convert traces")
+		}
+		capture.setTraces(traces)
+
+		if traces.SpanCount() > 0 {
+			if err := r.tracesConsumer.ConsumeTraces(ctx, traces); err != nil {
+				r.logger.Error("Failed to consume traces", zap.Error(err))
+				capture.warnf("failed to consume traces: %v", err)
+				return r.newConsumerPingError(err, "Failed to process traces")
+			}
+		}
+		gleanRequest.Delivery.mark(deliveryTraces)
+	}
+
+	// Stitch events into their session trace if session reconstruction is enabled
+	if r.tracesConsumer != nil && r.sessions != nil && !gleanRequest.Delivery.has(deliverySessions) {
+		traces := gleanRequest.Delivery.stitch(func() ptrace.Traces {
+			return r.sessions.stitch(&ping, clientKey, sessionKey)
+		})
+		if traces.SpanCount() > 0 {
+			if err := r.tracesConsumer.ConsumeTraces(ctx, traces); err != nil {
+				r.logger.Error("Failed to consume session traces", zap.Error(err))
+				return r.newConsumerPingError(err, "Failed to process traces")
+			}
+		}
+		gleanRequest.Delivery.mark(deliverySessions)
+	}
+
+	return nil
+}
+
+// applyPrivacy pseudonymizes client identifiers and redacts personal data
+// from string values before any signal is produced
+func (r *gleanReceiver) applyPrivacy(ctx context.Context, ping *GleanPing, capture *debugPing) {
+	if r.pseudonymizer != nil {
+		r.pseudonymizer.apply(&ping.ClientInfo)
+	}
+
+	if r.redactor != nil {
+		redactions := r.redactor.apply(ping)
+		r.telemetry.recordRedactions(ctx, redactions)
+		for rule, count := range redactions {
+			capture.warnf("%d value(s) redacted by rule %s", count, rule)
+		}
+	}
+}
+
+// handleDeletionRequest purges every piece of state held for the client of a
+// deletion-request ping, emits a structured deletion log and forwards the raw
+// ping to the dedicated deletion endpoint if configured. Replayed deletion
+// requests are not forwarded again. clientKey is the
+// key of the raw client_id the state is held under.
+func (r *gleanReceiver) handleDeletionRequest(ctx context.Context, ping *GleanPing, body []byte, clientKey string) error {
+	r.logger.Info("Received deletion-request ping",
+		zap.String("namespace", ping.Request.Namespace),
+		zap.String("document_id", ping.Request.DocumentID))
+
+	r.purgeClientState(clientKey)
+
+	if r.deletionForward != nil && !ping.Request.Replayed && !ping.Request.Delivery.has(deliveryDeletionForward) {
+		if err := r.deletionForward.forwardRawPing(context.Background(), ping.Request, body); err != nil {
+			r.logger.Error("Failed to forward deletion-request ping",
+				zap.Error(err),
+				zap.String("downstream_url", r.cfg.DeletionRequest.ForwardURL))
 		}
+		ping.Request.Delivery.mark(deliveryDeletionForward)
+	}
+
+	if r.logsConsumer == nil {
+		return nil
+	}
+	logs, err := convertToDeletionLog(ping, r.converter)
+	if err != nil {
+		return err
+	}
+	return r.logsConsumer.ConsumeLogs(ctx, logs)
+}
+
+// consumeValidationErrorLog sends the schema violations of a ping to the logs pipeline
+func (r *gleanReceiver) consumeValidationErrorLog(ctx context.Context, gleanRequest GleanPingRequest, violations []schemaViolation) error {
+	if r.logsConsumer == nil {
+		return nil
+	}
+	logs, err := convertToValidationErrorLog(gleanRequest, violations, r.converter)
+	if err != nil {
+		return err
 	}
+	return r.logsConsumer.ConsumeLogs(ctx, logs)
+}
 
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, "OK")
+// purgeClientState drops all per-client state the receiver holds under
+// clientKey
+func (r *gleanReceiver) purgeClientState(clientKey string) {
+	if r.sessions != nil {
+		purged := r.sessions.purgeClient(clientKey)
+		r.logger.Debug("Purged client sessions", zap.Int("sessions", purged))
+	}
+	if r.rateLimiter != nil {
+		r.rateLimiter.purge(rateLimitKeyClientID, clientKey)
+	}
+	if r.debug != nil {
+		purged := r.debug.purgeClient(clientKey)
+		r.logger.Debug("Purged client debug pings", zap.Int("pings", purged))
+	}
 }
@@ -1,20 +1,29 @@
 package gleanreceiver
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/client"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumererror"
 	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/receiver/receivertest"
 )
 
@@ -29,6 +38,7 @@ func TestReceiverStartStop(t *testing.T) {
 		receivertest.NewNopSettings(component.MustNewType("glean")),
 		consumertest.NewNop(),
 		consumertest.NewNop(),
+		nil,
 	)
 	require.NoError(t, err)
 
@@ -57,6 +67,7 @@ func TestReceiverHandleInvalidMethod(t *testing.T) {
 		receivertest.NewNopSettings(component.MustNewType("glean")),
 		metricsSink,
 		logsSink,
+		nil,
 	)
 	require.NoError(t, err)
 
@@ -91,6 +102,7 @@ func TestReceiverHandleInvalidJSON(t *testing.T) {
 		receivertest.NewNopSettings(component.MustNewType("glean")),
 		metricsSink,
 		logsSink,
+		nil,
 	)
 	require.NoError(t, err)
 
@@ -129,6 +141,7 @@ func TestReceiverHandleValidPing(t *testing.T) {
 		receivertest.NewNopSettings(component.MustNewType("glean")),
 		metricsSink,
 		logsSink,
+		nil,
 	)
 	require.NoError(t, err)
 
@@ -199,6 +212,824 @@ func TestReceiverHandleValidPing(t *testing.T) {
 	}, time.Second, 10*time.Millisecond)
 }
 
+func TestReceiverHandleValidPingTraces(t *testing.T) {
+	cfg := &Config{
+		Path: "/test",
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19897"
+
+	tracesSink := new(consumertest.TracesSink)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		nil,
+		nil,
+		tracesSink,
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	// Give server time to start
+	time.Sleep(100 * time.Millisecond)
+
+	ping := GleanPing{
+		ClientInfo: ClientInfo{ClientID: "test-client"},
+		PingInfo: PingInfo{
+			Seq:       1,
+			StartTime: time.Now(),
+			EndTime:   time.Now().Add(time.Minute),
+			PingType:  "metrics",
+		},
+		Metrics: map[string]any{
+			"timespan": map[string]any{
+				"app.startup": map[string]any{"time_unit": "millisecond", "value": 120},
+			},
+		},
+	}
+
+	body, err := json.Marshal(ping)
+	require.NoError(t, err)
+
+	resp, err := http.Post(
+		"http://localhost:19897/test/test-ns/metrics/1/test-doc-123",
+		"application/json",
+		bytes.NewBuffer(body),
+	)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// One root span for the ping plus one child span for the timespan
+	assert.Eventually(t, func() bool {
+		return tracesSink.SpanCount() == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestReceiverPseudonymizesClientID(t *testing.T) {
+	cfg := &Config{
+		Path: "/test",
+		Privacy: PrivacyConfig{
+			IdentifierMode: identifierModeHash,
+			HashKey:        "secret",
+		},
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19898"
+
+	metricsSink := new(consumertest.MetricsSink)
+	logsSink := new(consumertest.LogsSink)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		metricsSink,
+		logsSink,
+		nil,
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	// Give server time to start
+	time.Sleep(100 * time.Millisecond)
+
+	ping := GleanPing{
+		ClientInfo: ClientInfo{ClientID: "test-client"},
+		PingInfo:   PingInfo{Seq: 1, StartTime: time.Now(), EndTime: time.Now(), PingType: "metrics"},
+		Metrics: map[string]any{
+			"counter": map[string]any{"test_counter": float64(5)},
+		},
+		Events: []Event{{Timestamp: 0, Category: "test", Name: "test_event"}},
+	}
+	body, err := json.Marshal(ping)
+	require.NoError(t, err)
+
+	resp, err := http.Post(
+		"http://localhost:19898/test/test-ns/metrics/1/test-doc-123",
+		"application/json",
+		bytes.NewBuffer(body),
+	)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	expected := receiver.pseudonymizer.pseudonymize("test-client")
+	require.Eventually(t, func() bool {
+		return len(metricsSink.AllMetrics()) > 0 && len(logsSink.AllLogs()) > 0
+	}, time.Second, 10*time.Millisecond)
+
+	// Metrics and logs carry the same pseudonymized client ID
+	metricsClientID, _ := metricsSink.AllMetrics()[0].ResourceMetrics().At(0).Resource().Attributes().Get("client.id")
+	assert.Equal(t, expected, metricsClientID.Str())
+	logsClientID, _ := logsSink.AllLogs()[0].ResourceLogs().At(0).Resource().Attributes().Get("client.id")
+	assert.Equal(t, expected, logsClientID.Str())
+}
+
+func TestReceiverHandleDeletionRequest(t *testing.T) {
+	received := make(chan string, 1)
+	deletionEndpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		received <- r.URL.Path
+	}))
+	defer deletionEndpoint.Close()
+
+	cfg := &Config{
+		Path:            "/test",
+		Sessions:        SessionsConfig{Enabled: true, IdleTimeout: time.Hour},
+		DeletionRequest: DeletionRequestConfig{ForwardURL: deletionEndpoint.URL},
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19899"
+
+	metricsSink := new(consumertest.MetricsSink)
+	logsSink := new(consumertest.LogsSink)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		metricsSink,
+		logsSink,
+		consumertest.NewNop(),
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	// Give server time to start
+	time.Sleep(100 * time.Millisecond)
+
+	// Open a session for the client
+	receiver.sessions.stitch(&GleanPing{
+		ClientInfo: ClientInfo{ClientID: "test-client", SessionID: "test-session"},
+		Events:     []Event{{Category: "ui", Name: "click"}},
+	}, receiver.clientKeys.key("test-client"), "test-session")
+	require.Equal(t, 1, receiver.sessions.len())
+
+	ping := GleanPing{
+		ClientInfo: ClientInfo{ClientID: "test-client"},
+		PingInfo:   PingInfo{Seq: 0, StartTime: time.Now(), EndTime: time.Now(), PingType: "deletion-request", Reason: "set_upload_enabled"},
+		Metrics: map[string]any{
+			"counter": map[string]any{"test_counter": float64(5)},
+		},
+	}
+	body, err := json.Marshal(ping)
+	require.NoError(t, err)
+
+	resp, err := http.Post(
+		"http://localhost:19899/test/test-ns/deletion-request/1/test-doc-123",
+		"application/json",
+		bytes.NewBuffer(body),
+	)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Client state is purged
+	assert.Equal(t, 0, receiver.sessions.len())
+
+	// A single deletion log is emitted and metrics are not converted
+	require.Len(t, logsSink.AllLogs(), 1)
+	record := logsSink.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+	eventName, _ := record.Attributes().Get("event.name")
+	assert.Equal(t, "glean.deletion_request", eventName.Str())
+	clientID, _ := record.Attributes().Get("client.id")
+	assert.Equal(t, "test-client", clientID.Str())
+	assert.Empty(t, metricsSink.AllMetrics())
+
+	// The raw ping is forwarded to the deletion endpoint
+	select {
+	case path := <-received:
+		assert.Equal(t, "/test-ns/deletion-request/1/test-doc-123", path)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for deletion endpoint to receive ping")
+	}
+}
+
+func TestReceiverDeletionRequestBypassesGates(t *testing.T) {
+	// Every other ping is outside the document type allowlist, dropped by its source tag
+	// and fails validation and the event limit
+	schemaPath := filepath.Join(t.TempDir(), "schema.json")
+	require.NoError(t, os.WriteFile(schemaPath, []byte(`{"type": "object", "required": ["never"]}`), 0o600))
+
+	cfg := &Config{
+		Path:             "/test",
+		Sessions:         SessionsConfig{Enabled: true, IdleTimeout: time.Hour},
+		Privacy:          PrivacyConfig{IdentifierMode: identifierModeDrop},
+		SchemaValidation: SchemaValidationConfig{Enabled: true, SchemaPath: schemaPath},
+		Limits:           LimitsConfig{MaxEvents: 1, Action: limitActionReject},
+		Allowlist:        AllowlistConfig{Namespaces: []string{"test-ns"}, DocumentTypes: []string{"metrics"}},
+		SourceTags: SourceTagsConfig{
+			Rules: map[string]SourceTagRule{"automation": {Action: sourceTagDrop}},
+		},
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19922"
+
+	logsSink := new(consumertest.LogsSink)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		nil,
+		logsSink,
+		consumertest.NewNop(),
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	// Sessions are purged by the raw client_id even though it is dropped
+	receiver.sessions.stitch(&GleanPing{
+		ClientInfo: ClientInfo{SessionID: "test-session"},
+		Events:     []Event{{Category: "ui", Name: "click"}},
+	}, receiver.clientKeys.key("test-client"), "test-session")
+	require.Equal(t, 1, receiver.sessions.len())
+
+	send := func(namespace, clientID string) int {
+		body, err := json.Marshal(GleanPing{
+			ClientInfo: ClientInfo{ClientID: clientID},
+			PingInfo:   PingInfo{StartTime: time.Now(), EndTime: time.Now(), PingType: "deletion-request"},
+			Events:     []Event{{Category: "ui", Name: "a"}, {Category: "ui", Name: "b"}},
+		})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "http://localhost:19922/test/"+namespace+"/deletion-request/1/doc-1", bytes.NewBuffer(body))
+		require.NoError(t, err)
+		req.Header.Set(sourceTagsHeader, "automation")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// The namespace allowlist still applies
+	assert.Equal(t, http.StatusForbidden, send("other-ns", "test-client"))
+	assert.Equal(t, 1, receiver.sessions.len())
+	assert.Empty(t, logsSink.AllLogs())
+
+	assert.Equal(t, http.StatusOK, send("test-ns", "test-client"))
+	assert.Equal(t, 0, receiver.sessions.len())
+
+	// The deletion log does not carry the dropped client_id
+	require.Len(t, logsSink.AllLogs(), 1)
+	record := logsSink.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+	_, exists := record.Attributes().Get("client.id")
+	assert.False(t, exists)
+
+	// A deletion request without client_id cannot purge anything
+	assert.Equal(t, http.StatusBadRequest, send("test-ns", ""))
+	assert.Len(t, logsSink.AllLogs(), 1)
+}
+
+func TestReceiverSchemaValidation(t *testing.T) {
+	tests := []struct {
+		name           string
+		onFailure      string
+		endpoint       string
+		expectedStatus int
+		expectedLogs   int
+	}{
+		{"reject", validationFailureReject, "localhost:19900", http.StatusBadRequest, 0},
+		{"log", validationFailureLog, "localhost:19901", http.StatusOK, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Path: "/test",
+				SchemaValidation: SchemaValidationConfig{
+					Enabled:   true,
+					OnFailure: tt.onFailure,
+				},
+			}
+			cfg.ServerConfig.NetAddr.Endpoint = tt.endpoint
+
+			metricsSink := new(consumertest.MetricsSink)
+			logsSink := new(consumertest.LogsSink)
+
+			receiver, err := newGleanReceiver(
+				cfg,
+				receivertest.NewNopSettings(component.MustNewType("glean")),
+				metricsSink,
+				logsSink,
+				nil,
+			)
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			err = receiver.Start(ctx, componenttest.NewNopHost())
+			require.NoError(t, err)
+			defer receiver.Shutdown(ctx)
+
+			// Give server time to start
+			time.Sleep(100 * time.Millisecond)
+
+			// ping_info.seq must be an integer
+			resp, err := http.Post(
+				"http://"+tt.endpoint+"/test/test-ns/metrics/1/test-doc-123",
+				"application/json",
+				bytes.NewBufferString(`{"client_info": {}, "ping_info": {"seq": "1", "start_time": "", "end_time": ""}, "metrics": {"counter": {"a.b": 1}}}`),
+			)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			assert.Empty(t, metricsSink.AllMetrics())
+			require.Len(t, logsSink.AllLogs(), tt.expectedLogs)
+
+			if tt.expectedLogs > 0 {
+				record := logsSink.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+				errs, ok := record.Attributes().Get("glean.validation.errors")
+				require.True(t, ok)
+				require.Equal(t, 1, errs.Slice().Len())
+				assert.Contains(t, errs.Slice().At(0).Str(), "/ping_info/seq")
+			}
+		})
+	}
+}
+
+func TestReceiverSchemaValidationOmitsValues(t *testing.T) {
+	cfg := &Config{
+		Path: "/test",
+		SchemaValidation: SchemaValidationConfig{
+			Enabled:   true,
+			OnFailure: validationFailureLog,
+		},
+		Debug: DebugConfig{
+			Enabled:       true,
+			Endpoint:      "localhost:19929",
+			Path:          "/debug",
+			MaxPingsPerID: 10,
+			MaxDebugIDs:   10,
+		},
+		Privacy: PrivacyConfig{IdentifierMode: identifierModeDrop},
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19928"
+
+	logsSink := new(consumertest.LogsSink)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		consumertest.NewNop(),
+		logsSink,
+		nil,
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	// Give server time to start
+	time.Sleep(100 * time.Millisecond)
+
+	// client_id does not match the UUID pattern
+	req, err := http.NewRequest(http.MethodPost,
+		"http://localhost:19928/test/test-ns/metrics/1/test-doc-123",
+		bytes.NewBufferString(`{"client_info": {"client_id": "secret-client-id"}, "ping_info": {"seq": 1, "start_time": "2024-01-28T10:00:00Z", "end_time": "2024-01-28T10:01:00Z"}}`))
+	require.NoError(t, err)
+	req.Header.Set(debugIDHeader, "my-debug-tag")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.Len(t, logsSink.AllLogs(), 1)
+	record := logsSink.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+	errs, ok := record.Attributes().Get("glean.validation.errors")
+	require.True(t, ok)
+	require.Equal(t, 1, errs.Slice().Len())
+	assert.Equal(t, "/client_info/client_id: pattern", errs.Slice().At(0).Str())
+	assert.NotContains(t, errs.AsString(), "secret-client-id")
+
+	pings := receiver.debug.pingsFor("my-debug-tag")
+	require.Len(t, pings, 1)
+	require.Len(t, pings[0].Warnings, 1)
+	assert.NotContains(t, pings[0].Warnings[0], "secret-client-id")
+}
+
+func TestReceiverAllowlist(t *testing.T) {
+	cfg := &Config{
+		Path: "/test",
+		Allowlist: AllowlistConfig{
+			Namespaces: []string{"allowed-*"},
+		},
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19902"
+
+	metricsSink := new(consumertest.MetricsSink)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		metricsSink,
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	// Give server time to start
+	time.Sleep(100 * time.Millisecond)
+
+	ping := GleanPing{
+		PingInfo: PingInfo{Seq: 1, StartTime: time.Now(), EndTime: time.Now(), PingType: "metrics"},
+		Metrics: map[string]any{
+			"counter": map[string]any{"test_counter": float64(5)},
+		},
+	}
+	body, err := json.Marshal(ping)
+	require.NoError(t, err)
+
+	resp, err := http.Post("http://localhost:19902/test/other-ns/metrics/1/test-doc-123", "application/json", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Empty(t, metricsSink.AllMetrics())
+
+	resp, err = http.Post("http://localhost:19902/test/allowed-ns/metrics/1/test-doc-123", "application/json", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, metricsSink.AllMetrics(), 1)
+}
+
+func TestReceiverResponseStatus(t *testing.T) {
+	cfg := &Config{
+		Path:       "/test",
+		RetryAfter: 90 * time.Second,
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19916"
+	cfg.MaxRequestBodySize = 1024
+
+	var consumeErr error
+	metricsConsumer, err := consumer.NewMetrics(func(context.Context, pmetric.Metrics) error {
+		return consumeErr
+	})
+	require.NoError(t, err)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		metricsConsumer,
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	const url = "http://localhost:19916/test/test-app/metrics/1/doc-1"
+
+	tests := []struct {
+		name       string
+		consumeErr error
+		body       io.Reader
+		wantStatus int
+		retryAfter string
+	}{
+		{
+			name:       "accepted",
+			body:       strings.NewReader(envelopePingBody),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "permanent consumer error",
+			consumeErr: consumererror.NewPermanent(errors.New("invalid metrics")),
+			body:       strings.NewReader(envelopePingBody),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "backpressure",
+			consumeErr: errors.New("memory limit exceeded"),
+			body:       strings.NewReader(envelopePingBody),
+			wantStatus: http.StatusServiceUnavailable,
+			retryAfter: "90",
+		},
+		{
+			name:       "oversized",
+			body:       strings.NewReader(strings.Repeat(" ", 2048) + envelopePingBody),
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			// Without a Content-Length the limit applies while reading
+			name:       "oversized chunked",
+			body:       io.MultiReader(strings.NewReader(strings.Repeat(" ", 2048)), strings.NewReader(envelopePingBody)),
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumeErr = tt.consumeErr
+			resp, err := http.Post(url, "application/json", tt.body)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.Equal(t, tt.retryAfter, resp.Header.Get("Retry-After"))
+		})
+	}
+}
+
+func TestReceiverRequestMetadata(t *testing.T) {
+	cfg := &Config{
+		Path:           "/test",
+		RequestHeaders: []string{"X-Debug-ID"},
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19903"
+
+	metricsSink := new(consumertest.MetricsSink)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		metricsSink,
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	// Give server time to start
+	time.Sleep(100 * time.Millisecond)
+
+	ping := GleanPing{
+		PingInfo: PingInfo{Seq: 1, StartTime: time.Now(), EndTime: time.Now(), PingType: "metrics"},
+		Metrics: map[string]any{
+			"counter": map[string]any{"test_counter": float64(5)},
+		},
+	}
+	body, err := json.Marshal(ping)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, "http://localhost:19903/test/test-app/metrics/1/test-doc-123", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	req.Header.Set("X-Debug-ID", "my-debug-tag")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.Len(t, metricsSink.AllMetrics(), 1)
+	// Per-ping identifiers stay off the metric resource
+	attrs := metricsSink.AllMetrics()[0].ResourceMetrics().At(0).Resource().Attributes()
+	_, exists := attrs.Get("document.id")
+	assert.False(t, exists)
+	_, exists = attrs.Get("glean.submission_timestamp")
+	assert.False(t, exists)
+	debugID, exists := attrs.Get("http.request.header.x-debug-id")
+	assert.True(t, exists)
+	assert.Equal(t, "my-debug-tag", debugID.Str())
+
+	md := client.FromContext(metricsSink.Contexts()[0]).Metadata
+	assert.Equal(t, []string{"test-doc-123"}, md.Get("document_id"))
+	assert.Equal(t, []string{"my-debug-tag"}, md.Get("X-Debug-ID"))
+}
+
+func TestReceiverDebugView(t *testing.T) {
+	cfg := &Config{
+		Path: "/test",
+		Debug: DebugConfig{
+			Enabled:       true,
+			Endpoint:      "localhost:19923",
+			Path:          "/debug",
+			MaxPingsPerID: 10,
+			MaxDebugIDs:   10,
+		},
+		Privacy: PrivacyConfig{IdentifierMode: identifierModeDrop},
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19904"
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		consumertest.NewNop(),
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	// Give server time to start
+	time.Sleep(100 * time.Millisecond)
+
+	ping := GleanPing{
+		ClientInfo: ClientInfo{ClientID: "test-client-id"},
+		PingInfo:   PingInfo{Seq: 1, StartTime: time.Now(), EndTime: time.Now(), PingType: "metrics"},
+		Metrics: map[string]any{
+			"counter": map[string]any{"test_counter": float64(5)},
+		},
+	}
+	body, err := json.Marshal(ping)
+	require.NoError(t, err)
+
+	post := func(documentID string, body []byte, debugID string) {
+		req, err := http.NewRequest(http.MethodPost, "http://localhost:19904/test/test-app/metrics/1/"+documentID, bytes.NewBuffer(body))
+		require.NoError(t, err)
+		if debugID != "" {
+			req.Header.Set(debugIDHeader, debugID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	post("untagged-doc", body, "")
+	post("tagged-doc", body, "my-debug-tag")
+	post("invalid-doc", []byte("{invalid"), "my-debug-tag")
+
+	pings := receiver.debug.pingsFor("my-debug-tag")
+	require.Len(t, pings, 2)
+
+	invalid := pings[0]
+	assert.Equal(t, "invalid-doc", invalid.DocumentID)
+	assert.Equal(t, http.StatusBadRequest, invalid.Status)
+	require.Len(t, invalid.Warnings, 1)
+	assert.Contains(t, invalid.Warnings[0], "invalid JSON")
+
+	tagged := pings[1]
+	assert.Equal(t, "tagged-doc", tagged.DocumentID)
+	assert.Equal(t, http.StatusOK, tagged.Status)
+	// The captured ping is the one exported, after privacy settings
+	assert.Contains(t, string(tagged.Ping), "test_counter")
+	assert.NotContains(t, string(tagged.Ping), "test-client-id")
+	assert.Contains(t, string(tagged.Metrics), "counter.test_counter")
+	assert.Empty(t, tagged.Warnings)
+
+	// The debug view is only served on its own endpoint
+	resp, err := http.Get("http://localhost:19904/debug/api/debug-ids")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, err = http.Get("http://localhost:19923/debug/api/debug-ids")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// A deletion request purges the captured pings of the client
+	deletion, err := http.Post("http://localhost:19904/test/test-app/deletion-request/1/deletion-doc", "application/json", bytes.NewBufferString(`{"client_info":{"client_id":"test-client-id"},"ping_info":{"seq":0}}`))
+	require.NoError(t, err)
+	deletion.Body.Close()
+	assert.Equal(t, http.StatusOK, deletion.StatusCode)
+	pings = receiver.debug.pingsFor("my-debug-tag")
+	require.Len(t, pings, 1)
+	assert.Equal(t, "invalid-doc", pings[0].DocumentID)
+}
+
+func TestReceiverDebugTail(t *testing.T) {
+	cfg := &Config{
+		Path: "/test",
+		Debug: DebugConfig{
+			Enabled:       true,
+			Endpoint:      "localhost:19924",
+			Path:          "/debug",
+			MaxPingsPerID: 10,
+			MaxDebugIDs:   10,
+		},
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19905"
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		consumertest.NewNop(),
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	// Give server time to start
+	time.Sleep(100 * time.Millisecond)
+
+	tail, err := http.Get("http://localhost:19924/debug/tail?namespace=test-app&client_id=test-client-id")
+	require.NoError(t, err)
+	defer tail.Body.Close()
+
+	ping := GleanPing{
+		ClientInfo: ClientInfo{ClientID: "test-client-id"},
+		PingInfo:   PingInfo{Seq: 1, StartTime: time.Now(), EndTime: time.Now(), PingType: "metrics"},
+		Metrics: map[string]any{
+			"counter": map[string]any{"test_counter": float64(5)},
+		},
+	}
+	body, err := json.Marshal(ping)
+	require.NoError(t, err)
+
+	for _, namespace := range []string{"other-app", "test-app"} {
+		resp, err := http.Post("http://localhost:19905/test/"+namespace+"/metrics/1/test-doc-123", "application/json", bytes.NewBuffer(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	// Untagged pings are streamed but not kept by the debug view
+	reader := bufio.NewReader(tail.Body)
+	_, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	data, err := reader.ReadString('\n')
+	require.NoError(t, err)
+
+	var captured debugPing
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(data, "data: ")), &captured))
+	assert.Equal(t, "test-app", captured.Namespace)
+	assert.Equal(t, http.StatusOK, captured.Status)
+	assert.Contains(t, string(captured.Ping), "test_counter")
+	assert.Empty(t, receiver.debug.debugIDs())
+}
+
+func TestReceiverSourceTags(t *testing.T) {
+	cfg := &Config{
+		Path: "/test",
+		SourceTags: SourceTagsConfig{
+			Rules: map[string]SourceTagRule{
+				"automation": {Action: sourceTagDrop},
+				"qa":         {Action: sourceTagRoute, Route: "qa"},
+			},
+		},
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19906"
+
+	metricsSink := new(consumertest.MetricsSink)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		metricsSink,
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	// Give server time to start
+	time.Sleep(100 * time.Millisecond)
+
+	ping := GleanPing{
+		PingInfo: PingInfo{Seq: 1, StartTime: time.Now(), EndTime: time.Now(), PingType: "metrics"},
+		Metrics: map[string]any{
+			"counter": map[string]any{"test_counter": float64(5)},
+		},
+	}
+	body, err := json.Marshal(ping)
+	require.NoError(t, err)
+
+	post := func(sourceTags string) int {
+		req, err := http.NewRequest(http.MethodPost, "http://localhost:19906/test/test-app/metrics/1/test-doc-123", bytes.NewBuffer(body))
+		require.NoError(t, err)
+		req.Header.Set(sourceTagsHeader, sourceTags)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Dropped pings are acknowledged but not converted
+	assert.Equal(t, http.StatusOK, post("automation"))
+	assert.Empty(t, metricsSink.AllMetrics())
+
+	assert.Equal(t, http.StatusOK, post("qa"))
+	require.Len(t, metricsSink.AllMetrics(), 1)
+	route, exists := metricsSink.AllMetrics()[0].ResourceMetrics().At(0).Resource().Attributes().Get("glean.route")
+	assert.True(t, exists)
+	assert.Equal(t, "qa", route.Str())
+	assert.Equal(t, []string{"qa"}, client.FromContext(metricsSink.Contexts()[0]).Metadata.Get("glean.route"))
+}
+
 func TestReceiverMultipleStarts(t *testing.T) {
 	cfg := &Config{
 		Path: "/test",
@@ -210,6 +1041,7 @@ func TestReceiverMultipleStarts(t *testing.T) {
 		receivertest.NewNopSettings(component.MustNewType("glean")),
 		consumertest.NewNop(),
 		consumertest.NewNop(),
+		nil,
 	)
 	require.NoError(t, err)
 
@@ -276,6 +1108,7 @@ func TestForwardRawPing(t *testing.T) {
 		receivertest.NewNopSettings(component.MustNewType("glean")),
 		consumertest.NewNop(),
 		consumertest.NewNop(),
+		nil,
 	)
 	require.NoError(t, err)
 	require.NotNil(t, receiver.forwarder, "Forwarder should be created when ForwardURL is set")
@@ -346,6 +1179,7 @@ func TestForwardRawPingNoConfig(t *testing.T) {
 		receivertest.NewNopSettings(component.MustNewType("glean")),
 		consumertest.NewNop(),
 		consumertest.NewNop(),
+		nil,
 	)
 	require.NoError(t, err)
 
@@ -396,6 +1230,7 @@ func TestForwardRawPingFailure(t *testing.T) {
 		receivertest.NewNopSettings(component.MustNewType("glean")),
 		consumertest.NewNop(),
 		consumertest.NewNop(),
+		nil,
 	)
 	require.NoError(t, err)
 
@@ -447,6 +1282,7 @@ func TestForwardRawPingTimeout(t *testing.T) {
 		receivertest.NewNopSettings(component.MustNewType("glean")),
 		consumertest.NewNop(),
 		consumertest.NewNop(),
+		nil,
 	)
 	require.NoError(t, err)
 
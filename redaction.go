@@ -0,0 +1,151 @@
+package gleanreceiver
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+// Redaction rules, used as the rule attribute of the redaction counter
+const (
+	redactionRuleDeny      = "deny"
+	redactionRulePattern   = "pattern"
+	redactionRuleURLQuery  = "url_query"
+	redactionRuleMaxLength = "max_length"
+)
+
+// redactedMetricTypes are the Glean metric types holding free-form strings
+var redactedMetricTypes = []string{"string", "text", "url", "string_list", "labeled_string"}
+
+// redactor removes personal data from string metrics and event extras
+// before a ping is converted
+type redactor struct {
+	patterns      []*regexp.Regexp
+	allow         map[string]bool
+	deny          map[string]bool
+	stripURLQuery bool
+	maxLength     int
+	replacement   string
+}
+
+// newRedactor creates a new instance of redactor. It returns nil when no
+// redaction rule is configured.
+func newRedactor(cfg RedactionConfig) (*redactor, error) {
+	if len(cfg.Patterns) == 0 && len(cfg.DenyMetrics) == 0 && !cfg.StripURLQuery && cfg.MaxLength == 0 {
+		return nil, nil
+	}
+
+	patterns := make([]*regexp.Regexp, 0, len(cfg.Patterns))
+	for _, pattern := range cfg.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		patterns = append(patterns, re)
+	}
+
+	replacement := cfg.Replacement
+	if replacement == "" {
+		replacement = "[REDACTED]"
+	}
+
+	return &redactor{
+		patterns:      patterns,
+		allow:         toSet(cfg.AllowMetrics),
+		deny:          toSet(cfg.DenyMetrics),
+		stripURLQuery: cfg.StripURLQuery,
+		maxLength:     cfg.MaxLength,
+		replacement:   replacement,
+	}, nil
+}
+
+// apply redacts the ping in place and returns, for each redaction rule, the
+// number of values it changed. A value changed by several rules is counted
+// once for each of them.
+func (r *redactor) apply(ping *GleanPing) map[string]int {
+	counts := make(map[string]int)
+
+	for _, metricType := range redactedMetricTypes {
+		metricsOfType, ok := ping.Metrics[metricType].(map[string]any)
+		if !ok {
+			continue
+		}
+
+		for name, value := range metricsOfType {
+			switch v := value.(type) {
+			case string:
+				metricsOfType[name] = r.redact(name, metricType, v, counts)
+			case []any:
+				for i, item := range v {
+					if s, ok := item.(string); ok {
+						v[i] = r.redact(name, metricType, s, counts)
+					}
+				}
+			case map[string]any:
+				// labeled_string values, keyed by label
+				for label, item := range v {
+					if s, ok := item.(string); ok {
+						v[label] = r.redact(name, metricType, s, counts)
+					}
+				}
+			}
+		}
+	}
+
+	for _, event := range ping.Events {
+		name := event.Category + "." + event.Name
+		for key, value := range event.Extra {
+			event.Extra[key] = r.redact(name, "event", value, counts)
+		}
+	}
+
+	return counts
+}
+
+// redact returns the redacted form of a single value of the metric or event
+// name, counting the rules that changed it
+func (r *redactor) redact(name string, metricType string, value string, counts map[string]int) string {
+	if r.allow[name] {
+		return value
+	}
+	if r.deny[name] {
+		counts[redactionRuleDeny]++
+		return r.replacement
+	}
+
+	if r.stripURLQuery && metricType == "url" {
+		if u, err := url.Parse(value); err == nil && (u.RawQuery != "" || u.Fragment != "") {
+			u.RawQuery = ""
+			u.Fragment = ""
+			value = u.String()
+			counts[redactionRuleURLQuery]++
+		}
+	}
+
+	redacted := false
+	for _, re := range r.patterns {
+		if re.MatchString(value) {
+			value = re.ReplaceAllString(value, r.replacement)
+			redacted = true
+		}
+	}
+	if redacted {
+		counts[redactionRulePattern]++
+	}
+
+	if r.maxLength > 0 && len([]rune(value)) > r.maxLength {
+		value = string([]rune(value)[:r.maxLength])
+		counts[redactionRuleMaxLength]++
+	}
+
+	return value
+}
+
+// toSet converts a list of names to a lookup set
+func toSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
@@ -0,0 +1,128 @@
+package gleanreceiver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactorApply(t *testing.T) {
+	r, err := newRedactor(RedactionConfig{
+		Patterns:      []string{`[\w.+-]+@[\w-]+\.[\w.]+`},
+		AllowMetrics:  []string{"search.engine"},
+		DenyMetrics:   []string{"search.query"},
+		StripURLQuery: true,
+		MaxLength:     20,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, r)
+
+	ping := &GleanPing{
+		Metrics: map[string]any{
+			"string": map[string]any{
+				"search.engine":  "contact me@example.com",
+				"search.query":   "something private",
+				"profile.owner":  "owner is me@example.com",
+				"profile.status": "active",
+			},
+			"text": map[string]any{
+				"crash.notes": "a very long text value that exceeds the limit",
+			},
+			"url": map[string]any{
+				"page.last": "https://a.io/p?token=secret#frag",
+			},
+			"string_list": map[string]any{
+				"profile.contacts": []any{"you@example.com", "nobody"},
+			},
+			"labeled_string": map[string]any{
+				"profile.emails": map[string]any{"work": "we@example.com", "home": "none"},
+			},
+			"counter": map[string]any{
+				"app.opened": float64(1),
+			},
+		},
+		Events: []Event{
+			{
+				Category: "login",
+				Name:     "submitted",
+				Extra:    map[string]string{"email": "me@example.com", "method": "password"},
+			},
+		},
+	}
+
+	counts := r.apply(ping)
+
+	strings := ping.Metrics["string"].(map[string]any)
+	assert.Equal(t, "contact me@example.com", strings["search.engine"], "allowlisted metric is untouched")
+	assert.Equal(t, "[REDACTED]", strings["search.query"], "denylisted metric is replaced")
+	assert.Equal(t, "owner is [REDACTED]", strings["profile.owner"])
+	assert.Equal(t, "active", strings["profile.status"])
+
+	assert.Equal(t, "a very long text val", ping.Metrics["text"].(map[string]any)["crash.notes"])
+	assert.Equal(t, "https://a.io/p", ping.Metrics["url"].(map[string]any)["page.last"])
+	assert.Equal(t, []any{"[REDACTED]", "nobody"}, ping.Metrics["string_list"].(map[string]any)["profile.contacts"])
+	assert.Equal(t, map[string]any{"work": "[REDACTED]", "home": "none"}, ping.Metrics["labeled_string"].(map[string]any)["profile.emails"])
+	assert.Equal(t, float64(1), ping.Metrics["counter"].(map[string]any)["app.opened"])
+
+	assert.Equal(t, "[REDACTED]", ping.Events[0].Extra["email"])
+	assert.Equal(t, "password", ping.Events[0].Extra["method"])
+
+	assert.Equal(t, map[string]int{
+		redactionRuleDeny:      1,
+		redactionRulePattern:   4,
+		redactionRuleURLQuery:  1,
+		redactionRuleMaxLength: 1,
+	}, counts)
+}
+
+func TestRedactorCountsEachRule(t *testing.T) {
+	r, err := newRedactor(RedactionConfig{
+		Patterns:  []string{`secret`, `token`},
+		MaxLength: 10,
+	})
+	require.NoError(t, err)
+
+	ping := &GleanPing{
+		Metrics: map[string]any{
+			"text": map[string]any{
+				"crash.notes": "secret token in a long note",
+			},
+		},
+	}
+
+	// Both patterns and the truncation change the same value: it is counted
+	// once by each rule
+	counts := r.apply(ping)
+	assert.Equal(t, "[REDACTED]", ping.Metrics["text"].(map[string]any)["crash.notes"])
+	assert.Equal(t, map[string]int{
+		redactionRulePattern:   1,
+		redactionRuleMaxLength: 1,
+	}, counts)
+}
+
+func TestRedactorDenyEventExtras(t *testing.T) {
+	r, err := newRedactor(RedactionConfig{
+		DenyMetrics: []string{"search.performed"},
+		Replacement: "***",
+	})
+	require.NoError(t, err)
+
+	ping := &GleanPing{
+		Events: []Event{
+			{Category: "search", Name: "performed", Extra: map[string]string{"query": "private"}},
+		},
+	}
+	r.apply(ping)
+
+	assert.Equal(t, "***", ping.Events[0].Extra["query"])
+}
+
+func TestNewRedactor(t *testing.T) {
+	r, err := newRedactor(RedactionConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, r, "no rules means no redactor")
+
+	_, err = newRedactor(RedactionConfig{Patterns: []string{"("}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,179 @@
+package gleanreceiver
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Policies for metrics and pings missing from the registry
+const (
+	undeclaredAccept = "accept"
+	undeclaredFlag   = "flag"
+	undeclaredDrop   = "drop"
+	undeclaredReject = "reject"
+)
+
+// builtinPings are sent by every Glean SDK and never declared in pings.yaml
+var builtinPings = []string{"baseline", "metrics", "events", "deletion-request", "health"}
+
+// metricDefinition is a metric declared in a Glean metrics.yaml file
+type metricDefinition struct {
+	Type        string `yaml:"type"`
+	Description string `yaml:"description"`
+	Unit        string `yaml:"unit"`
+	TimeUnit    string `yaml:"time_unit"`
+	MemoryUnit  string `yaml:"memory_unit"`
+}
+
+// pingDefinition is a ping declared in a Glean pings.yaml file
+type pingDefinition struct {
+	Description string `yaml:"description"`
+}
+
+// gleanRegistry holds the probe definitions loaded from metrics.yaml and
+// pings.yaml files, keyed by Glean identifier (category.name for metrics)
+type gleanRegistry struct {
+	metrics map[string]metricDefinition
+	pings   map[string]pingDefinition
+}
+
+// loadRegistry parses the given metrics.yaml and pings.yaml files
+func loadRegistry(metricsFiles []string, pingsFiles []string) (*gleanRegistry, error) {
+	registry := &gleanRegistry{
+		metrics: make(map[string]metricDefinition),
+		pings:   make(map[string]pingDefinition),
+	}
+
+	for _, file := range metricsFiles {
+		var categories map[string]yaml.Node
+		if err := readYAML(file, &categories); err != nil {
+			return nil, err
+		}
+		for category, categoryNode := range categories {
+			if isRegistryKeyword(category) {
+				continue
+			}
+			var metrics map[string]yaml.Node
+			if err := categoryNode.Decode(&metrics); err != nil {
+				return nil, fmt.Errorf("invalid category %s in %s: %w", category, file, err)
+			}
+			for name, node := range metrics {
+				if isRegistryKeyword(name) {
+					continue
+				}
+				var definition metricDefinition
+				if err := node.Decode(&definition); err != nil {
+					return nil, fmt.Errorf("invalid definition of metric %s.%s in %s: %w", category, name, file, err)
+				}
+				registry.metrics[category+"."+name] = definition
+			}
+		}
+	}
+
+	for _, file := range pingsFiles {
+		var pings map[string]yaml.Node
+		if err := readYAML(file, &pings); err != nil {
+			return nil, err
+		}
+		for name, node := range pings {
+			if isRegistryKeyword(name) {
+				continue
+			}
+			var definition pingDefinition
+			if err := node.Decode(&definition); err != nil {
+				return nil, fmt.Errorf("invalid definition of ping %s in %s: %w", name, file, err)
+			}
+			registry.pings[name] = definition
+		}
+	}
+
+	return registry, nil
+}
+
+// readYAML reads and decodes a YAML file
+func readYAML(file string, out any) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read registry file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse registry file %s: %w", file, err)
+	}
+	return nil
+}
+
+// isRegistryKeyword reports whether a registry key is a schema keyword
+// ($schema, $tags, no_lint) rather than a category, metric or ping name
+func isRegistryKeyword(key string) bool {
+	return strings.HasPrefix(key, "$") || key == "no_lint"
+}
+
+// lookupMetric returns the definition of the metric with the given Glean identifier
+func (r *gleanRegistry) lookupMetric(name string) (metricDefinition, bool) {
+	definition, ok := r.metrics[name]
+	return definition, ok
+}
+
+// undeclaredMetrics returns the Glean identifiers of the metrics in a ping
+// that are not declared in the registry
+func (r *gleanRegistry) undeclaredMetrics(ping *GleanPing) []string {
+	var undeclared []string
+	for _, categoryData := range ping.Metrics {
+		categoryMap, ok := categoryData.(map[string]any)
+		if !ok {
+			continue
+		}
+		for name := range categoryMap {
+			if _, ok := r.metrics[name]; !ok {
+				undeclared = append(undeclared, name)
+			}
+		}
+	}
+	slices.Sort(undeclared)
+	return undeclared
+}
+
+// hasPing reports whether the ping type is declared or built into Glean
+func (r *gleanRegistry) hasPing(name string) bool {
+	if _, ok := r.pings[name]; ok {
+		return true
+	}
+	return slices.Contains(builtinPings, name)
+}
+
+// otelUnit returns the UCUM unit of the values a metric reports in pings
+func (d metricDefinition) otelUnit() string {
+	switch d.Type {
+	case "timing_distribution", "labeled_timing_distribution":
+		// Timing distributions are always reported in nanoseconds
+		return "ns"
+	case "memory_distribution", "labeled_memory_distribution":
+		// Memory distributions are always reported in bytes
+		return "By"
+	case "timespan":
+		timeUnit := d.TimeUnit
+		if timeUnit == "" {
+			timeUnit = "millisecond"
+		}
+		return timeUnitSymbols[timeUnit]
+	}
+	if d.Unit != "" {
+		return d.Unit
+	}
+	return "1"
+}
+
+// timeUnitSymbols maps Glean time_unit values to UCUM units
+var timeUnitSymbols = map[string]string{
+	"nanosecond":  "ns",
+	"microsecond": "us",
+	"millisecond": "ms",
+	"second":      "s",
+	"minute":      "min",
+	"hour":        "h",
+	"day":         "d",
+}
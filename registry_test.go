@@ -0,0 +1,222 @@
+package gleanreceiver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+const testMetricsYAML = `
+$schema: moz://mozilla.org/schemas/glean/metrics/2-0-0
+
+app:
+  opened:
+    type: counter
+    description: Number of times the app was opened.
+  startup:
+    type: timespan
+    description: Time to first paint.
+    time_unit: millisecond
+  page_load:
+    type: timing_distribution
+    description: Page load time.
+  bytes_sent:
+    type: quantity
+    description: Bytes sent over the network.
+    unit: By
+  errors:
+    type: labeled_counter
+    description: Errors by kind.
+`
+
+const testPingsYAML = `
+$schema: moz://mozilla.org/schemas/glean/pings/2-0-0
+
+onboarding:
+  description: Sent when onboarding completes.
+  include_client_id: true
+`
+
+// writeTestRegistry writes the test metrics.yaml and pings.yaml files and
+// returns their paths
+func writeTestRegistry(t *testing.T) (string, string) {
+	dir := t.TempDir()
+	metricsFile := filepath.Join(dir, "metrics.yaml")
+	pingsFile := filepath.Join(dir, "pings.yaml")
+	require.NoError(t, os.WriteFile(metricsFile, []byte(testMetricsYAML), 0o600))
+	require.NoError(t, os.WriteFile(pingsFile, []byte(testPingsYAML), 0o600))
+	return metricsFile, pingsFile
+}
+
+func TestLoadRegistry(t *testing.T) {
+	metricsFile, pingsFile := writeTestRegistry(t)
+
+	registry, err := loadRegistry([]string{metricsFile}, []string{pingsFile})
+	require.NoError(t, err)
+
+	opened, ok := registry.lookupMetric("app.opened")
+	require.True(t, ok)
+	assert.Equal(t, "counter", opened.Type)
+	assert.Equal(t, "Number of times the app was opened.", opened.Description)
+	assert.Equal(t, "1", opened.otelUnit())
+
+	startup, ok := registry.lookupMetric("app.startup")
+	require.True(t, ok)
+	assert.Equal(t, "ms", startup.otelUnit())
+
+	pageLoad, _ := registry.lookupMetric("app.page_load")
+	assert.Equal(t, "ns", pageLoad.otelUnit())
+
+	bytesSent, _ := registry.lookupMetric("app.bytes_sent")
+	assert.Equal(t, "By", bytesSent.otelUnit())
+
+	_, ok = registry.lookupMetric("$schema.anything")
+	assert.False(t, ok)
+
+	assert.True(t, registry.hasPing("onboarding"))
+	assert.True(t, registry.hasPing("metrics"), "built-in pings are always declared")
+	assert.False(t, registry.hasPing("unknown"))
+}
+
+func TestLoadRegistryErrors(t *testing.T) {
+	_, err := loadRegistry([]string{filepath.Join(t.TempDir(), "missing.yaml")}, nil)
+	assert.Error(t, err)
+
+	invalid := filepath.Join(t.TempDir(), "metrics.yaml")
+	require.NoError(t, os.WriteFile(invalid, []byte("app: [not, a, map"), 0o600))
+	_, err = loadRegistry([]string{invalid}, nil)
+	assert.Error(t, err)
+}
+
+func TestRegistryUndeclaredMetrics(t *testing.T) {
+	metricsFile, _ := writeTestRegistry(t)
+	registry, err := loadRegistry([]string{metricsFile}, nil)
+	require.NoError(t, err)
+
+	ping := &GleanPing{
+		Metrics: map[string]any{
+			"counter": map[string]any{
+				"app.opened":  float64(1),
+				"app.unknown": float64(2),
+			},
+			"string": map[string]any{
+				"app.other": "value",
+			},
+		},
+	}
+
+	assert.Equal(t, []string{"app.other", "app.unknown"}, registry.undeclaredMetrics(ping))
+}
+
+func TestConvertToMetricsWithRegistry(t *testing.T) {
+	metricsFile, pingsFile := writeTestRegistry(t)
+	registry, err := loadRegistry([]string{metricsFile}, []string{pingsFile})
+	require.NoError(t, err)
+
+	ping := &GleanPing{
+		Request: GleanPingRequest{DocumentType: "custom"},
+		Metrics: map[string]any{
+			"counter": map[string]any{
+				"app.opened":    float64(5),
+				"app.undeclare": float64(1),
+			},
+			"timespan": map[string]any{
+				"app.startup": map[string]any{"time_unit": "millisecond", "value": float64(120)},
+			},
+			"labeled_counter": map[string]any{
+				"app.errors": map[string]any{"timeout": float64(2), "dns": float64(1)},
+			},
+		},
+	}
+
+	tests := []struct {
+		name              string
+		undeclaredMetrics string
+		expectedMetrics   int
+		expectFlag        bool
+	}{
+		{"accept", undeclaredAccept, 4, false},
+		{"flag", undeclaredFlag, 4, true},
+		{"drop", undeclaredDrop, 3, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics, err := convertToMetrics(ping, converterSettings{
+				registry:          registry,
+				undeclaredMetrics: tt.undeclaredMetrics,
+				undeclaredPings:   undeclaredFlag,
+			})
+			require.NoError(t, err)
+
+			scopeMetrics := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+			require.Equal(t, tt.expectedMetrics, scopeMetrics.Metrics().Len())
+
+			// The ping type is not declared
+			flagged, ok := scopeMetrics.Scope().Attributes().Get("glean.undeclared")
+			assert.True(t, ok)
+			assert.True(t, flagged.Bool())
+
+			for i := 0; i < scopeMetrics.Metrics().Len(); i++ {
+				metric := scopeMetrics.Metrics().At(i)
+				switch metric.Name() {
+				case "counter.app.opened":
+					// Declared counters become monotonic sums instead of gauges
+					assert.Equal(t, pmetric.MetricTypeSum, metric.Type())
+					assert.Equal(t, int64(5), metric.Sum().DataPoints().At(0).IntValue())
+					assert.Equal(t, "Number of times the app was opened.", metric.Description())
+				case "timespan.app.startup":
+					assert.Equal(t, "ms", metric.Unit())
+					assert.Equal(t, 120.0, metric.Gauge().DataPoints().At(0).DoubleValue())
+				case "labeled_counter.app.errors":
+					require.Equal(t, 2, metric.Sum().DataPoints().Len())
+					label, _ := metric.Sum().DataPoints().At(0).Attributes().Get("label")
+					assert.Equal(t, "dns", label.Str())
+				case "counter.app.undeclare":
+					_, flagged := metric.Gauge().DataPoints().At(0).Attributes().Get("glean.undeclared")
+					assert.Equal(t, tt.expectFlag, flagged)
+				default:
+					t.Errorf("unexpected metric %s", metric.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestConvertToMetricsDeclaredTypeMismatch(t *testing.T) {
+	metricsFile, _ := writeTestRegistry(t)
+	registry, err := loadRegistry([]string{metricsFile}, nil)
+	require.NoError(t, err)
+
+	// Metrics whose value does not have the shape of their declared type
+	// are converted from their JSON value
+	ping := &GleanPing{
+		Metrics: map[string]any{
+			"counter": map[string]any{
+				"app.page_load": float64(3),
+				"app.opened":    "five",
+			},
+			"timespan": map[string]any{
+				"app.startup": float64(120),
+			},
+			"labeled_counter": map[string]any{
+				"app.errors": float64(1),
+			},
+		},
+	}
+
+	metrics, err := convertToMetrics(ping, converterSettings{registry: registry})
+	require.NoError(t, err)
+
+	scopeMetrics := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+	require.Equal(t, 4, scopeMetrics.Metrics().Len())
+	for i := 0; i < scopeMetrics.Metrics().Len(); i++ {
+		metric := scopeMetrics.Metrics().At(i)
+		assert.Equal(t, pmetric.MetricTypeGauge, metric.Type(), metric.Name())
+		assert.Empty(t, metric.Description(), metric.Name())
+	}
+}
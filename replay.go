@@ -0,0 +1,169 @@
+package gleanreceiver
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxReplayRate is the highest replay rate, one ping per microsecond. The
+// ticker interval of higher rates would round down to zero.
+const maxReplayRate = 1e6
+
+// parseArchivedPing parses one archive line into a replayed ping request and
+// its body. Times are moved by timeShift.
+func parseArchivedPing(line []byte, timeShift time.Duration, maxDecompressed int64) (GleanPingRequest, []byte, error) {
+	gleanRequest, body, err := parsePingEnvelope(line, maxDecompressed)
+	if err != nil {
+		return GleanPingRequest{}, nil, err
+	}
+
+	gleanRequest.Replayed = true
+	gleanRequest.TimeShift = timeShift
+	if gleanRequest.SubmissionTime.IsZero() {
+		gleanRequest.SubmissionTime = time.Now()
+	} else {
+		gleanRequest.SubmissionTime = gleanRequest.SubmissionTime.Add(timeShift)
+	}
+	return gleanRequest, body, nil
+}
+
+// replayStats counts the outcome of replayed pings
+type replayStats struct {
+	replayed int
+	refused  int
+	failed   int
+}
+
+// replayArchives replays the configured archives once, stopping early if the
+// receiver shuts down
+func (r *gleanReceiver) replayArchives() {
+	defer r.background.Done()
+
+	var paths []string
+	for _, pattern := range r.cfg.Replay.Paths {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			r.logger.Warn("Invalid replay path", zap.String("path", pattern), zap.Error(err))
+			continue
+		}
+		if len(matches) == 0 {
+			r.logger.Warn("Replay path matches no files", zap.String("path", pattern))
+		}
+		paths = append(paths, matches...)
+	}
+	slices.Sort(paths)
+	paths = slices.Compact(paths)
+
+	// Pace pings to the configured rate
+	var ticker *time.Ticker
+	if r.cfg.Replay.Rate > 0 {
+		ticker = time.NewTicker(time.Duration(float64(time.Second) / r.cfg.Replay.Rate))
+		defer ticker.Stop()
+	}
+
+	var stats replayStats
+	for _, path := range paths {
+		if err := r.replayArchive(path, ticker, &stats); err != nil {
+			if errors.Is(err, errReplayStopped) {
+				r.logger.Info("Archive replay stopped by shutdown", zap.String("file", path))
+				return
+			}
+			r.logger.Warn("Failed to replay archive", zap.String("file", path), zap.Error(err))
+		}
+	}
+
+	r.logger.Info("Finished replaying archives",
+		zap.Int("files", len(paths)),
+		zap.Int("replayed", stats.replayed),
+		zap.Int("refused", stats.refused),
+		zap.Int("failed", stats.failed))
+}
+
+// errReplayStopped is returned when the receiver shuts down during replay
+var errReplayStopped = errors.New("replay stopped")
+
+// replayArchive replays every line of a single archive file
+func (r *gleanReceiver) replayArchive(path string, ticker *time.Ticker, stats *replayStats) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	if magic, _ := reader.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return err
+		}
+		defer gzipReader.Close()
+		reader = bufio.NewReader(gzipReader)
+	}
+
+	for lineNumber := 1; ; lineNumber++ {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return readErr
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if ticker != nil {
+				select {
+				case <-ticker.C:
+				case <-r.stop:
+					return errReplayStopped
+				}
+			} else {
+				select {
+				case <-r.stop:
+					return errReplayStopped
+				default:
+				}
+			}
+			r.replayLine(path, lineNumber, line, stats)
+		}
+
+		if readErr != nil {
+			return nil
+		}
+	}
+}
+
+// replayLine runs a single archived ping through the receiver
+func (r *gleanReceiver) replayLine(path string, lineNumber int, line []byte, stats *replayStats) {
+	gleanRequest, body, err := parseArchivedPing(line, r.cfg.Replay.TimeShift, r.cfg.maxDecompressedBytes())
+	if errors.Is(err, errDecompressedTooLarge) {
+		r.telemetry.recordLimitedPing(context.Background(), limitDecompressedBytes, limitActionReject)
+	}
+	if err != nil {
+		stats.failed++
+		r.logger.Warn("Skipping malformed archived ping",
+			zap.String("file", path), zap.Int("line", lineNumber), zap.Error(err))
+		return
+	}
+
+	err = r.ingestPing(context.Background(), gleanRequest, func() ([]byte, error) { return body, nil })
+	switch status := pingStatus(err); {
+	case status >= http.StatusInternalServerError:
+		stats.failed++
+		r.logger.Warn("Failed to replay archived ping",
+			zap.String("file", path), zap.Int("line", lineNumber), zap.Error(err))
+	case err != nil:
+		stats.refused++
+		r.logger.Debug("Archived ping refused",
+			zap.String("file", path), zap.Int("line", lineNumber), zap.Error(err))
+	default:
+		stats.replayed++
+	}
+}
@@ -0,0 +1,143 @@
+package gleanreceiver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+)
+
+func TestParseArchivedPing(t *testing.T) {
+	line := `{"uri":"/submit/test-app/metrics/1/doc-1","submission_timestamp":"2024-01-28T10:02:00Z","payload":` + envelopePingBody + `}`
+	gleanRequest, _, err := parseArchivedPing([]byte(line), 24*time.Hour, defaultMaxRequestBodySize)
+	require.NoError(t, err)
+	assert.True(t, gleanRequest.Replayed)
+	assert.Equal(t, 24*time.Hour, gleanRequest.TimeShift)
+	assert.Equal(t, time.Date(2024, 1, 29, 10, 2, 0, 0, time.UTC), gleanRequest.SubmissionTime)
+
+	// Envelopes without a submission timestamp are submitted now
+	line = `{"uri":"/submit/test-app/metrics/1/doc-1","payload":` + envelopePingBody + `}`
+	gleanRequest, _, err = parseArchivedPing([]byte(line), 24*time.Hour, defaultMaxRequestBodySize)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), gleanRequest.SubmissionTime, time.Minute)
+
+	_, _, err = parseArchivedPing([]byte("not json"), 0, defaultMaxRequestBodySize)
+	assert.Error(t, err)
+}
+
+func TestReceiverReplay(t *testing.T) {
+	dir := t.TempDir()
+	lines := []string{
+		`{"uri":"/submit/test-app/metrics/1/doc-1","submission_timestamp":"2024-01-28T10:02:00Z","payload":` + envelopePingBody + `}`,
+		`not json`,
+		``,
+		`{"uri":"/submit/test-app/metrics/1/doc-2","payload":` + envelopePingBody + `}`,
+	}
+	archive := gzipBytes(t, []byte(strings.Join(lines, "\n")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pings.ndjson.gz"), archive, 0o600))
+
+	cfg := &Config{
+		Path: "/test",
+		Replay: ReplayConfig{
+			Paths:     []string{filepath.Join(dir, "*.ndjson.gz")},
+			Rate:      100,
+			TimeShift: 24 * time.Hour,
+		},
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19908"
+
+	metricsSink := new(consumertest.MetricsSink)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		metricsSink,
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	assert.Eventually(t, func() bool {
+		return len(metricsSink.AllMetrics()) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// Data points are placed at the shifted end of the ping window
+	shiftedEnd := time.Date(2024, 1, 29, 10, 1, 0, 0, time.UTC)
+	for _, metrics := range metricsSink.AllMetrics() {
+		dp := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0)
+		assert.Equal(t, shiftedEnd, dp.Timestamp().AsTime())
+	}
+
+	md := client.FromContext(metricsSink.Contexts()[0]).Metadata
+	assert.Equal(t, []string{"2024-01-29T10:02:00Z"}, md.Get("submission_timestamp"))
+}
+
+func TestReceiverReplayDoesNotForward(t *testing.T) {
+	var forwarded atomic.Int32
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer downstream.Close()
+
+	dir := t.TempDir()
+	lines := []string{
+		`{"uri":"/submit/test-app/metrics/1/doc-1","payload":` + envelopePingBody + `}`,
+		`{"uri":"/submit/test-app/deletion-request/1/doc-2","payload":{"client_info":{"client_id":"test-client"},"ping_info":{"seq":0}}}`,
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pings.ndjson"), []byte(strings.Join(lines, "\n")), 0o600))
+
+	cfg := &Config{
+		Path:       "/test",
+		ForwardURL: downstream.URL,
+		DeletionRequest: DeletionRequestConfig{
+			ForwardURL: downstream.URL,
+		},
+		Replay: ReplayConfig{
+			Paths: []string{filepath.Join(dir, "*.ndjson")},
+			Rate:  100,
+		},
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19930"
+
+	metricsSink := new(consumertest.MetricsSink)
+	logsSink := new(consumertest.LogsSink)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		metricsSink,
+		logsSink,
+		nil,
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	// The metrics ping is converted and the deletion request logged
+	require.Eventually(t, func() bool {
+		return len(metricsSink.AllMetrics()) == 1 && len(logsSink.AllLogs()) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Zero(t, forwarded.Load())
+}
@@ -0,0 +1,101 @@
+package gleanreceiver
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Path wildcards that fill the ping request
+const (
+	wildcardNamespace       = "namespace"
+	wildcardDocumentType    = "document_type"
+	wildcardDocumentVersion = "document_version"
+	wildcardDocumentID      = "document_id"
+)
+
+// wildcardPattern matches the wildcards of a ServeMux pattern
+var wildcardPattern = regexp.MustCompile(`\{([^}.]+)(?:\.\.\.)?\}`)
+
+// routes returns every submission route, the main path first
+func (cfg *Config) routes() []RouteConfig {
+	return append([]RouteConfig{{Path: cfg.GetPath()}}, cfg.Routes...)
+}
+
+// wildcards returns the wildcard names captured by the route path
+func (route RouteConfig) wildcards() map[string]bool {
+	names := make(map[string]bool)
+	for _, match := range wildcardPattern.FindAllStringSubmatch(route.Path, -1) {
+		names[match[1]] = true
+	}
+	return names
+}
+
+// pingRequest builds the ping request for a request matching the route.
+// Document IDs are generated for routes that do not capture them.
+func (route RouteConfig) pingRequest(req *http.Request) GleanPingRequest {
+	gleanRequest := GleanPingRequest{
+		Namespace:       req.PathValue(wildcardNamespace),
+		DocumentType:    req.PathValue(wildcardDocumentType),
+		DocumentVersion: req.PathValue(wildcardDocumentVersion),
+		DocumentID:      req.PathValue(wildcardDocumentID),
+		Headers:         req.Header.Clone(),
+		SubmissionTime:  time.Now(),
+	}
+	if gleanRequest.Namespace == "" {
+		gleanRequest.Namespace = route.Namespace
+	}
+	if gleanRequest.DocumentType == "" {
+		gleanRequest.DocumentType = route.DocumentType
+	}
+	if gleanRequest.DocumentVersion == "" {
+		gleanRequest.DocumentVersion = route.DocumentVersion
+	}
+	if gleanRequest.DocumentID == "" {
+		gleanRequest.DocumentID = uuid.NewString()
+	}
+	return gleanRequest
+}
+
+// validateRoutes checks that every route is a valid pattern that does not
+// conflict with another and yields a complete ping request
+func validateRoutes(routes []RouteConfig) (err error) {
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, "/") {
+			return fmt.Errorf("route path %q must start with /", route.Path)
+		}
+
+		wildcards := route.wildcards()
+		for _, field := range []struct {
+			wildcard string
+			fixed    string
+		}{
+			{wildcardNamespace, route.Namespace},
+			{wildcardDocumentType, route.DocumentType},
+			{wildcardDocumentVersion, route.DocumentVersion},
+		} {
+			switch {
+			case wildcards[field.wildcard] && field.fixed != "":
+				return fmt.Errorf("route %s both captures and sets %s", route.Path, field.wildcard)
+			case !wildcards[field.wildcard] && field.fixed == "":
+				return fmt.Errorf("route %s must capture or set %s", route.Path, field.wildcard)
+			}
+		}
+	}
+
+	// ServeMux panics on invalid and conflicting patterns
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("invalid routes: %v", recovered)
+		}
+	}()
+	mux := http.NewServeMux()
+	for _, route := range routes {
+		mux.HandleFunc(route.Path, func(http.ResponseWriter, *http.Request) {})
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package gleanreceiver
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+)
+
+func TestValidateRoutes(t *testing.T) {
+	mainRoute := RouteConfig{Path: "/submit/{namespace}/{document_type}/{document_version}/{document_id}"}
+
+	tests := []struct {
+		name    string
+		route   RouteConfig
+		wantErr bool
+	}{
+		{
+			name: "legacy telemetry route",
+			route: RouteConfig{
+				Path:            "/submit/telemetry/{document_id}/{document_type}/{app}/{rest...}",
+				Namespace:       "telemetry",
+				DocumentVersion: "4",
+			},
+		},
+		{
+			name:  "short route",
+			route: RouteConfig{Path: "/glean/{document_type}", Namespace: "internal", DocumentVersion: "1"},
+		},
+		{
+			name:    "relative path",
+			route:   RouteConfig{Path: "glean/{document_type}", Namespace: "internal", DocumentVersion: "1"},
+			wantErr: true,
+		},
+		{
+			name:    "missing namespace",
+			route:   RouteConfig{Path: "/glean/{document_type}", DocumentVersion: "1"},
+			wantErr: true,
+		},
+		{
+			name:    "captured and fixed document type",
+			route:   RouteConfig{Path: "/glean/{document_type}", Namespace: "internal", DocumentType: "metrics", DocumentVersion: "1"},
+			wantErr: true,
+		},
+		{
+			name:    "conflicting with the main path",
+			route:   RouteConfig{Path: "/submit/{namespace}/{document_type}/{document_version}/{document_id}"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid pattern",
+			route:   RouteConfig{Path: "/glean/{document_type", Namespace: "internal", DocumentVersion: "1"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRoutes([]RouteConfig{mainRoute, tt.route})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRoutePingRequest(t *testing.T) {
+	route := RouteConfig{
+		Path:            "/submit/telemetry/{document_id}/{document_type}/{app}/{rest...}",
+		Namespace:       "telemetry",
+		DocumentVersion: "4",
+	}
+
+	var gleanRequest GleanPingRequest
+	mux := http.NewServeMux()
+	mux.HandleFunc(route.Path, func(_ http.ResponseWriter, req *http.Request) {
+		gleanRequest = route.pingRequest(req)
+	})
+	req := httptest.NewRequest(http.MethodPost, "/submit/telemetry/doc-1/main/Firefox/130.0/release/20240101", nil)
+	req.Header.Set("X-Debug-ID", "qa")
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "telemetry", gleanRequest.Namespace)
+	assert.Equal(t, "main", gleanRequest.DocumentType)
+	assert.Equal(t, "4", gleanRequest.DocumentVersion)
+	assert.Equal(t, "doc-1", gleanRequest.DocumentID)
+	assert.Equal(t, "qa", gleanRequest.Headers.Get("X-Debug-ID"))
+
+	// Routes without a document ID get a generated one
+	route = RouteConfig{Path: "/glean/{document_type}", Namespace: "internal", DocumentVersion: "1"}
+	mux = http.NewServeMux()
+	mux.HandleFunc(route.Path, func(_ http.ResponseWriter, req *http.Request) {
+		gleanRequest = route.pingRequest(req)
+	})
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/glean/metrics", nil))
+
+	assert.Equal(t, "internal", gleanRequest.Namespace)
+	assert.Equal(t, "metrics", gleanRequest.DocumentType)
+	_, err := uuid.Parse(gleanRequest.DocumentID)
+	assert.NoError(t, err)
+}
+
+func TestReceiverRoutes(t *testing.T) {
+	cfg := &Config{
+		Path: "/test",
+		Routes: []RouteConfig{
+			{Path: "/glean/{document_type}", Namespace: "internal-app", DocumentVersion: "1"},
+		},
+	}
+	cfg.ServerConfig.NetAddr.Endpoint = "localhost:19915"
+
+	metricsSink := new(consumertest.MetricsSink)
+
+	receiver, err := newGleanReceiver(
+		cfg,
+		receivertest.NewNopSettings(component.MustNewType("glean")),
+		metricsSink,
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = receiver.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer receiver.Shutdown(ctx)
+
+	for _, path := range []string{"/test/test-app/metrics/1/doc-1", "/glean/metrics"} {
+		resp, err := http.Post("http://localhost:19915"+path, "application/json", bytes.NewBufferString(envelopePingBody))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, path)
+	}
+
+	assert.Eventually(t, func() bool {
+		return len(metricsSink.AllMetrics()) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	attrs := metricsSink.AllMetrics()[1].ResourceMetrics().At(0).Resource().Attributes()
+	serviceName, exists := attrs.Get("service.name")
+	assert.True(t, exists)
+	assert.Equal(t, "internal-app", serviceName.Str())
+	documentType, exists := attrs.Get("document.type")
+	assert.True(t, exists)
+	assert.Equal(t, "metrics", documentType.Str())
+}
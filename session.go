@@ -0,0 +1,277 @@
+package gleanreceiver
+
+import (
+	"container/list"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// sessionStitcher groups events from pings sharing a session_id into one
+// trace. Sessions are held under a session key derived from the client key,
+// namespace and raw session_id, so clients sharing an exported session_id
+// are kept apart. Trace and span IDs are derived from the exported
+// identifiers, see sessionSeed, so events from different pings land in the
+// same trace. The session root span is emitted once the session has been
+// idle for the configured timeout, or once it is the least recently seen of
+// more than maxSessions sessions. Pings arriving after their session closed
+// open a new session in the same trace with its own root span.
+type sessionStitcher struct {
+	idleTimeout time.Duration
+	maxSessions int
+	attributes  *attributeMapper
+	now         func() time.Time
+
+	mu       sync.Mutex
+	sessions map[string]*sessionState
+	recent   *list.List
+}
+
+// sessionState tracks an open session between pings
+type sessionState struct {
+	clientKey string
+	// seed is what the trace and span IDs are derived from
+	seed string
+	// rootSpanID is derived from the ping that opened the session, so a
+	// session reopened by late pings gets a new root span
+	rootSpanID pcommon.SpanID
+	// sessionID is the exported session_id, empty when dropped
+	sessionID  string
+	namespace  string
+	clientInfo ClientInfo
+	start      time.Time
+	end        time.Time
+	lastSeen   time.Time
+	eventCount int
+	pingCount  int
+
+	// element is the session's entry in sessionStitcher.recent
+	element *list.Element
+}
+
+// newSessionStitcher creates a new instance of sessionStitcher
+func newSessionStitcher(cfg SessionsConfig, attributes *attributeMapper) *sessionStitcher {
+	return &sessionStitcher{
+		idleTimeout: cfg.IdleTimeout,
+		maxSessions: cfg.MaxSessions,
+		attributes:  attributes,
+		now:         time.Now,
+		sessions:    make(map[string]*sessionState),
+		recent:      list.New(),
+	}
+}
+
+// sessionSeed returns what the trace and span IDs of a session are derived
+// from: the exported client_id and session_id, so they are the same on every
+// receiver. When the privacy settings drop session_id, the session key is
+// used instead, which cannot be linked back to the raw identifiers.
+func sessionSeed(clientInfo *ClientInfo, sessionKey string) string {
+	if clientInfo.SessionID == "" {
+		return sessionKey
+	}
+	return clientInfo.ClientID + "/" + clientInfo.SessionID
+}
+
+// stitch converts the events of a ping into spans of its session trace.
+// clientKey is the key of the raw client_id the session is purged by and
+// sessionKey the key the session is held under, see
+// clientKeyer.sessionKey. Both are derived before privacy settings apply to
+// the ping. Pings without a session key or without events produce no spans.
+// When the ping opens a session over max_sessions, the root span of the
+// least recently seen session is closed and returned with the event spans.
+func (s *sessionStitcher) stitch(ping *GleanPing, clientKey, sessionKey string) ptrace.Traces {
+	traces := ptrace.NewTraces()
+	if sessionKey == "" || len(ping.Events) == 0 {
+		return traces
+	}
+
+	var first, last time.Time
+	for _, event := range ping.Events {
+		timestamp := ping.PingInfo.StartTime.Add(time.Duration(event.Timestamp) * time.Millisecond)
+		if first.IsZero() || timestamp.Before(first) {
+			first = timestamp
+		}
+		if timestamp.After(last) {
+			last = timestamp
+		}
+	}
+
+	// The session is updated in one critical section so expire never sees
+	// it without its time range
+	s.mu.Lock()
+	state, exists := s.sessions[sessionKey]
+	if exists {
+		s.recent.MoveToFront(state.element)
+	} else {
+		seed := sessionSeed(&ping.ClientInfo, sessionKey)
+		state = &sessionState{
+			clientKey:  clientKey,
+			seed:       seed,
+			rootSpanID: newSpanID(seed, fmt.Sprintf("session/%s/%d", ping.PingInfo.PingType, ping.PingInfo.Seq)),
+		}
+		state.element = s.recent.PushFront(sessionKey)
+		s.sessions[sessionKey] = state
+	}
+	var evicted *sessionState
+	if s.maxSessions > 0 && s.recent.Len() > s.maxSessions {
+		evictedKey := s.recent.Back().Value.(string)
+		evicted = s.sessions[evictedKey]
+		s.remove(evictedKey)
+	}
+	state.sessionID = ping.ClientInfo.SessionID
+	state.namespace = ping.Request.Namespace
+	state.clientInfo = ping.ClientInfo
+	state.lastSeen = s.now()
+	state.pingCount++
+	state.eventCount += len(ping.Events)
+	if state.start.IsZero() || first.Before(state.start) {
+		state.start = first
+	}
+	if last.After(state.end) {
+		state.end = last
+	}
+	seed, rootSpanID := state.seed, state.rootSpanID
+	s.mu.Unlock()
+
+	attrs := s.attributes.mapPing(ping)
+	rs := traces.ResourceSpans().AppendEmpty()
+	attrs.resource.CopyTo(rs.Resource().Attributes())
+
+	scopeSpans := rs.ScopeSpans().AppendEmpty()
+	scopeSpans.Scope().SetName("glean")
+	attrs.scope.CopyTo(scopeSpans.Scope().Attributes())
+	mergeAttributes(scopeSpans.Scope().Attributes(), attrs.pingInfo)
+
+	traceID := newTraceID(seed)
+
+	for i, event := range ping.Events {
+		timestamp := ping.PingInfo.StartTime.Add(time.Duration(event.Timestamp) * time.Millisecond)
+
+		span := scopeSpans.Spans().AppendEmpty()
+		span.SetTraceID(traceID)
+		span.SetSpanID(newSpanID(seed, fmt.Sprintf("%s/%d/%d", ping.PingInfo.PingType, ping.PingInfo.Seq, i)))
+		span.SetParentSpanID(rootSpanID)
+		span.SetName(fmt.Sprintf("%s.%s", event.Category, event.Name))
+		span.SetKind(ptrace.SpanKindInternal)
+		span.SetStartTimestamp(pcommon.NewTimestampFromTime(timestamp))
+		span.SetEndTimestamp(pcommon.NewTimestampFromTime(timestamp))
+		span.Attributes().PutStr("event.name", event.Name)
+		span.Attributes().PutStr("event.domain", event.Category)
+		for k, v := range event.Extra {
+			span.Attributes().PutStr(k, v)
+		}
+		mergeAttributes(span.Attributes(), attrs.dataPoint)
+		mergeAttributes(span.Attributes(), attrs.record)
+	}
+
+	// Sort spans by start time so the session reads in event order
+	scopeSpans.Spans().Sort(func(a, b ptrace.Span) bool {
+		return a.StartTimestamp() < b.StartTimestamp()
+	})
+
+	if evicted != nil {
+		s.appendRootSpan(traces, evicted)
+	}
+	return traces
+}
+
+// remove forgets a session. s.mu must be held.
+func (s *sessionStitcher) remove(sessionKey string) {
+	if state, ok := s.sessions[sessionKey]; ok {
+		s.recent.Remove(state.element)
+		delete(s.sessions, sessionKey)
+	}
+}
+
+// expire closes sessions idle for longer than the idle timeout and returns
+// their root spans
+func (s *sessionStitcher) expire() ptrace.Traces {
+	cutoff := s.now().Add(-s.idleTimeout)
+	return s.close(func(state *sessionState) bool {
+		return state.lastSeen.Before(cutoff)
+	})
+}
+
+// flush closes every open session and returns their root spans
+func (s *sessionStitcher) flush() ptrace.Traces {
+	return s.close(func(*sessionState) bool { return true })
+}
+
+// close removes the sessions matching shouldClose and returns their root spans
+func (s *sessionStitcher) close(shouldClose func(*sessionState) bool) ptrace.Traces {
+	s.mu.Lock()
+	var closed []*sessionState
+	for sessionKey, state := range s.sessions {
+		if shouldClose(state) {
+			closed = append(closed, state)
+			s.remove(sessionKey)
+		}
+	}
+	s.mu.Unlock()
+
+	// Emit sessions in a stable order
+	sort.Slice(closed, func(i, j int) bool {
+		return closed[i].seed < closed[j].seed
+	})
+
+	traces := ptrace.NewTraces()
+	for _, state := range closed {
+		s.appendRootSpan(traces, state)
+	}
+
+	return traces
+}
+
+// appendRootSpan adds the root span of a closed session to traces
+func (s *sessionStitcher) appendRootSpan(traces ptrace.Traces, state *sessionState) {
+	attrs := newMappedAttributes()
+	s.attributes.mapService(attrs, state.namespace, &state.clientInfo)
+	s.attributes.mapClientInfo(attrs, &state.clientInfo)
+	rs := traces.ResourceSpans().AppendEmpty()
+	attrs.resource.CopyTo(rs.Resource().Attributes())
+
+	scopeSpans := rs.ScopeSpans().AppendEmpty()
+	scopeSpans.Scope().SetName("glean")
+	attrs.scope.CopyTo(scopeSpans.Scope().Attributes())
+
+	span := scopeSpans.Spans().AppendEmpty()
+	span.SetTraceID(newTraceID(state.seed))
+	span.SetSpanID(state.rootSpanID)
+	span.SetName("session")
+	span.SetKind(ptrace.SpanKindInternal)
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(state.start))
+	span.SetEndTimestamp(pcommon.NewTimestampFromTime(state.end))
+	if state.sessionID != "" {
+		span.Attributes().PutStr("session.id", state.sessionID)
+	}
+	span.Attributes().PutInt("session.event_count", int64(state.eventCount))
+	span.Attributes().PutInt("session.ping_count", int64(state.pingCount))
+	mergeAttributes(span.Attributes(), attrs.dataPoint)
+}
+
+// purgeClient drops every open session of the client with clientKey without
+// emitting it and returns how many sessions were dropped
+func (s *sessionStitcher) purgeClient(clientKey string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for sessionKey, state := range s.sessions {
+		if state.clientKey == clientKey {
+			s.remove(sessionKey)
+			purged++
+		}
+	}
+	return purged
+}
+
+// len returns the number of open sessions
+func (s *sessionStitcher) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
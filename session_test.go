@@ -0,0 +1,248 @@
+package gleanreceiver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func newTestSessionPing(sessionID string, seq int, startTime time.Time, events ...Event) *GleanPing {
+	return &GleanPing{
+		ClientInfo: ClientInfo{
+			ClientID:  "test-client",
+			SessionID: sessionID,
+		},
+		PingInfo: PingInfo{
+			Seq:       seq,
+			StartTime: startTime,
+			EndTime:   startTime.Add(time.Minute),
+			PingType:  "events",
+		},
+		Events: events,
+	}
+}
+
+var testSessionKeys = newClientKeyer()
+
+// stitchTestPing stitches ping keyed by its client_id and session_id
+func stitchTestPing(stitcher *sessionStitcher, ping *GleanPing) ptrace.Traces {
+	clientKey := ping.ClientInfo.ClientID
+	return stitcher.stitch(ping, clientKey, testSessionKeys.sessionKey(clientKey, ping.Request.Namespace, ping.ClientInfo.SessionID))
+}
+
+func TestSessionStitcherStitch(t *testing.T) {
+	stitcher := newSessionStitcher(SessionsConfig{IdleTimeout: time.Minute}, nil)
+	startTime := time.Date(2024, 1, 28, 10, 0, 0, 0, time.UTC)
+
+	first := stitchTestPing(stitcher, newTestSessionPing("session-1", 0, startTime,
+		Event{Timestamp: 2000, Category: "ui", Name: "second"},
+		Event{Timestamp: 1000, Category: "ui", Name: "first"},
+	))
+	second := stitchTestPing(stitcher, newTestSessionPing("session-1", 1, startTime.Add(time.Minute),
+		Event{Timestamp: 0, Category: "ui", Name: "third"},
+	))
+
+	require.Equal(t, 2, first.SpanCount())
+	require.Equal(t, 1, second.SpanCount())
+	assert.Equal(t, 1, stitcher.len())
+
+	firstSpans := first.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	secondSpans := second.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+
+	// Events are ordered by time within a ping
+	assert.Equal(t, "ui.first", firstSpans.At(0).Name())
+	assert.Equal(t, "ui.second", firstSpans.At(1).Name())
+
+	// Events from both pings share the session trace and root span
+	assert.Equal(t, newTraceID("test-client/session-1"), firstSpans.At(0).TraceID())
+	assert.Equal(t, firstSpans.At(0).TraceID(), secondSpans.At(0).TraceID())
+	assert.Equal(t, firstSpans.At(0).ParentSpanID(), secondSpans.At(0).ParentSpanID())
+	assert.NotEqual(t, firstSpans.At(0).SpanID(), firstSpans.At(1).SpanID())
+}
+
+func TestSessionStitcherSkipsPingsWithoutSession(t *testing.T) {
+	stitcher := newSessionStitcher(SessionsConfig{IdleTimeout: time.Minute}, nil)
+
+	traces := stitchTestPing(stitcher, newTestSessionPing("", 0, time.Now(),
+		Event{Timestamp: 0, Category: "ui", Name: "click"},
+	))
+	assert.Equal(t, 0, traces.SpanCount())
+
+	traces = stitchTestPing(stitcher, newTestSessionPing("session-1", 0, time.Now()))
+	assert.Equal(t, 0, traces.SpanCount())
+	assert.Equal(t, 0, stitcher.len())
+}
+
+func TestSessionStitcherExpire(t *testing.T) {
+	now := time.Date(2024, 1, 28, 10, 0, 0, 0, time.UTC)
+	stitcher := newSessionStitcher(SessionsConfig{IdleTimeout: time.Minute}, nil)
+	stitcher.now = func() time.Time { return now }
+
+	stitchTestPing(stitcher, newTestSessionPing("session-1", 0, now,
+		Event{Timestamp: 1000, Category: "ui", Name: "open"},
+		Event{Timestamp: 9000, Category: "ui", Name: "close"},
+	))
+
+	// Not idle long enough yet
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, 0, stitcher.expire().SpanCount())
+	assert.Equal(t, 1, stitcher.len())
+
+	now = now.Add(time.Minute)
+	traces := stitcher.expire()
+	require.Equal(t, 1, traces.SpanCount())
+	assert.Equal(t, 0, stitcher.len())
+
+	root := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	assert.Equal(t, "session", root.Name())
+	assert.Equal(t, newTraceID("test-client/session-1"), root.TraceID())
+	assert.Equal(t, newSpanID("test-client/session-1", "session/events/0"), root.SpanID())
+	assert.True(t, root.ParentSpanID().IsEmpty())
+	assert.Equal(t, time.Date(2024, 1, 28, 10, 0, 1, 0, time.UTC).UnixNano(), root.StartTimestamp().AsTime().UnixNano())
+	assert.Equal(t, time.Date(2024, 1, 28, 10, 0, 9, 0, time.UTC).UnixNano(), root.EndTimestamp().AsTime().UnixNano())
+
+	eventCount, exists := root.Attributes().Get("session.event_count")
+	assert.True(t, exists)
+	assert.Equal(t, int64(2), eventCount.Int())
+}
+
+func TestSessionStitcherLatePing(t *testing.T) {
+	now := time.Date(2024, 1, 28, 10, 0, 0, 0, time.UTC)
+	stitcher := newSessionStitcher(SessionsConfig{IdleTimeout: time.Minute}, nil)
+	stitcher.now = func() time.Time { return now }
+
+	stitchTestPing(stitcher, newTestSessionPing("session-1", 0, now, Event{Category: "ui", Name: "a"}))
+	now = now.Add(2 * time.Minute)
+	closed := stitcher.expire()
+	require.Equal(t, 1, closed.SpanCount())
+
+	// A ping arriving after the session closed reopens it in the same trace
+	// under a new root span
+	late := stitchTestPing(stitcher, newTestSessionPing("session-1", 1, now, Event{Category: "ui", Name: "b"}))
+	require.Equal(t, 1, late.SpanCount())
+	lateSpan := late.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	reopened := stitcher.flush()
+	require.Equal(t, 1, reopened.SpanCount())
+
+	firstRoot := closed.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	secondRoot := reopened.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	assert.Equal(t, firstRoot.TraceID(), secondRoot.TraceID())
+	assert.NotEqual(t, firstRoot.SpanID(), secondRoot.SpanID())
+	assert.Equal(t, secondRoot.SpanID(), lateSpan.ParentSpanID())
+}
+
+func TestSessionStitcherFlush(t *testing.T) {
+	stitcher := newSessionStitcher(SessionsConfig{IdleTimeout: time.Hour}, nil)
+
+	stitchTestPing(stitcher, newTestSessionPing("session-1", 0, time.Now(), Event{Category: "ui", Name: "a"}))
+	stitchTestPing(stitcher, newTestSessionPing("session-2", 0, time.Now(), Event{Category: "ui", Name: "b"}))
+
+	traces := stitcher.flush()
+	assert.Equal(t, 2, traces.SpanCount())
+	assert.Equal(t, 0, stitcher.len())
+}
+
+func TestSessionStitcherMaxSessions(t *testing.T) {
+	now := time.Date(2024, 1, 28, 10, 0, 0, 0, time.UTC)
+	stitcher := newSessionStitcher(SessionsConfig{IdleTimeout: time.Hour, MaxSessions: 2}, nil)
+	stitcher.now = func() time.Time { return now }
+
+	stitchTestPing(stitcher, newTestSessionPing("session-1", 0, now, Event{Category: "ui", Name: "a"}))
+	stitchTestPing(stitcher, newTestSessionPing("session-2", 0, now, Event{Category: "ui", Name: "b"}))
+	// Seeing session-1 again makes session-2 the least recently seen
+	stitchTestPing(stitcher, newTestSessionPing("session-1", 1, now, Event{Category: "ui", Name: "c"}))
+
+	traces := stitchTestPing(stitcher, newTestSessionPing("session-3", 0, now, Event{Category: "ui", Name: "d"}))
+	assert.Equal(t, 2, stitcher.len())
+
+	// The evicted session's root span is emitted with the new event span
+	require.Equal(t, 2, traces.SpanCount())
+	root := traces.ResourceSpans().At(1).ScopeSpans().At(0).Spans().At(0)
+	assert.Equal(t, "session", root.Name())
+	sessionID, _ := root.Attributes().Get("session.id")
+	assert.Equal(t, "session-2", sessionID.Str())
+
+	traces = stitcher.flush()
+	assert.Equal(t, 2, traces.SpanCount())
+	assert.Equal(t, 0, stitcher.recent.Len())
+}
+
+func TestSessionStitcherPurgeClient(t *testing.T) {
+	stitcher := newSessionStitcher(SessionsConfig{IdleTimeout: time.Hour}, nil)
+
+	ping := newTestSessionPing("session-1", 0, time.Now(), Event{Category: "ui", Name: "a"})
+	stitchTestPing(stitcher, ping)
+	other := newTestSessionPing("session-2", 0, time.Now(), Event{Category: "ui", Name: "b"})
+	other.ClientInfo.ClientID = "other-client"
+	stitchTestPing(stitcher, other)
+
+	assert.Equal(t, 1, stitcher.purgeClient("test-client"))
+	assert.Equal(t, 1, stitcher.len())
+
+	// Purged sessions are not emitted
+	traces := stitcher.flush()
+	require.Equal(t, 1, traces.SpanCount())
+	sessionID, _ := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Attributes().Get("session.id")
+	assert.Equal(t, "session-2", sessionID.Str())
+}
+
+func TestSessionStitcherSharedSessionID(t *testing.T) {
+	stitcher := newSessionStitcher(SessionsConfig{IdleTimeout: time.Hour}, nil)
+
+	// Two clients whose exported session_id is the same, or dropped, keep
+	// separate sessions since they are keyed by the raw identifiers
+	stitchClient := func(clientID, exportedSessionID string) ptrace.Traces {
+		clientKey := testSessionKeys.key(clientID)
+		ping := newTestSessionPing(exportedSessionID, 0, time.Now(), Event{Category: "ui", Name: "a"})
+		ping.ClientInfo.ClientID = ""
+		return stitcher.stitch(ping, clientKey, testSessionKeys.sessionKey(clientKey, "", "session-1"))
+	}
+	first := stitchClient("client-1", "")
+	second := stitchClient("client-2", "")
+	require.Equal(t, 1, first.SpanCount())
+	require.Equal(t, 1, second.SpanCount())
+	assert.Equal(t, 2, stitcher.len())
+	assert.NotEqual(t,
+		first.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).TraceID(),
+		second.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).TraceID())
+
+	// Purging one client leaves the other's session open
+	assert.Equal(t, 1, stitcher.purgeClient(testSessionKeys.key("client-1")))
+	assert.Equal(t, 1, stitcher.len())
+
+	// Dropped session_ids are not exported on the root span
+	traces := stitcher.flush()
+	require.Equal(t, 1, traces.SpanCount())
+	root := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	_, exists := root.Attributes().Get("session.id")
+	assert.False(t, exists)
+}
+
+func TestSessionStitcherAttributeMapping(t *testing.T) {
+	mapper, err := newAttributeMapper(&Config{Attributes: AttributesConfig{
+		Mappings: map[string]AttributeMapping{
+			"client_id": {Name: "user.id"},
+			"seq":       {Drop: true},
+		},
+	}})
+	require.NoError(t, err)
+	stitcher := newSessionStitcher(SessionsConfig{IdleTimeout: time.Hour}, mapper)
+
+	traces := stitchTestPing(stitcher, newTestSessionPing("session-1", 0, time.Now(),
+		Event{Timestamp: 0, Category: "ui", Name: "click"},
+	))
+	rs := traces.ResourceSpans().At(0)
+	userID, exists := rs.Resource().Attributes().Get("user.id")
+	assert.True(t, exists)
+	assert.Equal(t, "test-client", userID.Str())
+	_, exists = rs.ScopeSpans().At(0).Scope().Attributes().Get("ping.seq")
+	assert.False(t, exists)
+
+	// The session root span uses the same mapping
+	flushed := stitcher.flush()
+	_, exists = flushed.ResourceSpans().At(0).Resource().Attributes().Get("user.id")
+	assert.True(t, exists)
+}
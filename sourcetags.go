@@ -0,0 +1,100 @@
+package gleanreceiver
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// sourceTagsHeader is the header Glean SDKs set to tag test and automation
+// traffic
+const sourceTagsHeader = "X-Source-Tags"
+
+// Actions applied to pings carrying a source tag
+const (
+	sourceTagDrop  = "drop"
+	sourceTagTag   = "tag"
+	sourceTagRoute = "route"
+)
+
+// refusedSourceTag is the refused pings reason for pings dropped by a source tag rule
+const refusedSourceTag = "source_tag"
+
+// sourceTagRouter decides what happens to pings based on their X-Source-Tags
+type sourceTagRouter struct {
+	rules map[string]SourceTagRule
+}
+
+// sourceTagDecision is the outcome of the source tag rules for one ping
+type sourceTagDecision struct {
+	// drop is set when the ping must not be processed
+	drop bool
+
+	// tags are the source tags to export as an attribute
+	tags []string
+
+	// route is the route name for routing connectors, if any
+	route string
+}
+
+// newSourceTagRouter creates a new instance of sourceTagRouter. It returns
+// nil when no rules are configured.
+func newSourceTagRouter(cfg SourceTagsConfig) (*sourceTagRouter, error) {
+	if len(cfg.Rules) == 0 {
+		return nil, nil
+	}
+
+	rules := make(map[string]SourceTagRule, len(cfg.Rules))
+	for tag, rule := range cfg.Rules {
+		switch rule.Action {
+		case sourceTagDrop, sourceTagTag:
+		case sourceTagRoute:
+			if rule.Route == "" {
+				return nil, fmt.Errorf("source_tags.rules.%s.route is required for the route action", tag)
+			}
+		default:
+			return nil, fmt.Errorf("invalid source_tags.rules.%s.action %q", tag, rule.Action)
+		}
+		rules[strings.ToLower(tag)] = rule
+	}
+
+	return &sourceTagRouter{rules: rules}, nil
+}
+
+// decide applies the rules to the source tags in headers. Dropping wins over
+// routing, and the first routed tag in header order picks the route.
+func (s *sourceTagRouter) decide(headers http.Header) sourceTagDecision {
+	var decision sourceTagDecision
+	for _, tag := range parseSourceTags(headers) {
+		rule, ok := s.rules[strings.ToLower(tag)]
+		if !ok {
+			continue
+		}
+		switch rule.Action {
+		case sourceTagDrop:
+			decision.drop = true
+		case sourceTagTag:
+			decision.tags = append(decision.tags, tag)
+		case sourceTagRoute:
+			decision.tags = append(decision.tags, tag)
+			if decision.route == "" {
+				decision.route = rule.Route
+			}
+		}
+	}
+	return decision
+}
+
+// parseSourceTags returns the comma-separated tags of the X-Source-Tags
+// header
+func parseSourceTags(headers http.Header) []string {
+	var tags []string
+	for _, value := range headers.Values(sourceTagsHeader) {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
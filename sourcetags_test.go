@@ -0,0 +1,99 @@
+package gleanreceiver
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSourceTagRouter(t *testing.T) {
+	router, err := newSourceTagRouter(SourceTagsConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, router)
+
+	tests := []struct {
+		name string
+		rule SourceTagRule
+	}{
+		{
+			name: "unknown action",
+			rule: SourceTagRule{Action: "ignore"},
+		},
+		{
+			name: "route without name",
+			rule: SourceTagRule{Action: sourceTagRoute},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newSourceTagRouter(SourceTagsConfig{
+				Rules: map[string]SourceTagRule{"automation": tt.rule},
+			})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSourceTagRouterDecide(t *testing.T) {
+	router, err := newSourceTagRouter(SourceTagsConfig{
+		Rules: map[string]SourceTagRule{
+			"automation": {Action: sourceTagDrop},
+			"perf":       {Action: sourceTagTag},
+			"QA":         {Action: sourceTagRoute, Route: "qa"},
+			"staging":    {Action: sourceTagRoute, Route: "staging"},
+		},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		header   string
+		expected sourceTagDecision
+	}{
+		{
+			name:     "no tags",
+			expected: sourceTagDecision{},
+		},
+		{
+			name:     "unknown tag",
+			header:   "manual",
+			expected: sourceTagDecision{},
+		},
+		{
+			name:     "tag",
+			header:   "perf",
+			expected: sourceTagDecision{tags: []string{"perf"}},
+		},
+		{
+			name:     "first route wins",
+			header:   "staging, qa, perf",
+			expected: sourceTagDecision{tags: []string{"staging", "qa", "perf"}, route: "staging"},
+		},
+		{
+			name:     "drop wins",
+			header:   "qa,automation",
+			expected: sourceTagDecision{drop: true, tags: []string{"qa"}, route: "qa"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := http.Header{}
+			if tt.header != "" {
+				headers.Set(sourceTagsHeader, tt.header)
+			}
+			assert.Equal(t, tt.expected, router.decide(headers))
+		})
+	}
+}
+
+func TestParseSourceTags(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(sourceTagsHeader, "automation, perf,")
+	headers.Add(sourceTagsHeader, "qa")
+	assert.Equal(t, []string{"automation", "perf", "qa"}, parseSourceTags(headers))
+	assert.Empty(t, parseSourceTags(http.Header{}))
+}
@@ -0,0 +1,108 @@
+package gleanreceiver
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/receiver"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const scopeName = "github.com/mozilla/gleanotelreceiver"
+
+// receiverTelemetry holds the instruments the receiver reports about itself
+// through the collector's own telemetry
+type receiverTelemetry struct {
+	redactedValues     metric.Int64Counter
+	validationFailures metric.Int64Counter
+	refusedPings       metric.Int64Counter
+	throttledPings     metric.Int64Counter
+	limitedPings       metric.Int64Counter
+}
+
+// newReceiverTelemetry creates the receiver's internal telemetry instruments
+func newReceiverTelemetry(set receiver.Settings) (*receiverTelemetry, error) {
+	meter := set.TelemetrySettings.MeterProvider.Meter(scopeName)
+
+	redactedValues, err := meter.Int64Counter(
+		"otelcol_receiver_glean_redacted_values",
+		metric.WithDescription("Number of Glean string values changed by each redaction rule. A value changed by several rules is counted once per rule."),
+		metric.WithUnit("{value}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	validationFailures, err := meter.Int64Counter(
+		"otelcol_receiver_glean_validation_failures",
+		metric.WithDescription("Number of Glean ping schema violations, by JSON pointer path"),
+		metric.WithUnit("{violation}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	refusedPings, err := meter.Int64Counter(
+		"otelcol_receiver_glean_refused_pings",
+		metric.WithDescription("Number of Glean pings refused before conversion, by reason"),
+		metric.WithUnit("{ping}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	throttledPings, err := meter.Int64Counter(
+		"otelcol_receiver_glean_throttled_pings",
+		metric.WithDescription("Number of Glean pings refused by rate limiting, by rate limit key"),
+		metric.WithUnit("{ping}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	limitedPings, err := meter.Int64Counter(
+		"otelcol_receiver_glean_limited_pings",
+		metric.WithDescription("Number of Glean pings over a payload limit, by limit and action"),
+		metric.WithUnit("{ping}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &receiverTelemetry{
+		redactedValues:     redactedValues,
+		validationFailures: validationFailures,
+		refusedPings:       refusedPings,
+		throttledPings:     throttledPings,
+		limitedPings:       limitedPings,
+	}, nil
+}
+
+// recordRedactions records the number of values each rule changed
+func (t *receiverTelemetry) recordRedactions(ctx context.Context, counts map[string]int) {
+	for rule, count := range counts {
+		t.redactedValues.Add(ctx, int64(count), metric.WithAttributes(attribute.String("rule", rule)))
+	}
+}
+
+// recordValidationFailures records schema violations by JSON pointer path
+func (t *receiverTelemetry) recordValidationFailures(ctx context.Context, violations []schemaViolation) {
+	for _, violation := range violations {
+		t.validationFailures.Add(ctx, 1, metric.WithAttributes(attribute.String("path", violation.pathPattern())))
+	}
+}
+
+// recordRefusedPing records a ping refused for the given reason
+func (t *receiverTelemetry) recordRefusedPing(ctx context.Context, reason string) {
+	t.refusedPings.Add(ctx, 1, metric.WithAttributes(attribute.String("reason", reason)))
+}
+
+// recordThrottledPing records a ping throttled by a rate limit on key
+func (t *receiverTelemetry) recordThrottledPing(ctx context.Context, key string) {
+	t.throttledPings.Add(ctx, 1, metric.WithAttributes(attribute.String("key", key)))
+}
+
+// recordLimitedPing records a ping over a payload limit and the action taken
+func (t *receiverTelemetry) recordLimitedPing(ctx context.Context, limit, action string) {
+	t.limitedPings.Add(ctx, 1, metric.WithAttributes(attribute.String("limit", limit), attribute.String("action", action)))
+}
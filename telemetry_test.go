@@ -0,0 +1,74 @@
+package gleanreceiver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// newTestTelemetry returns receiver telemetry backed by a manual reader
+func newTestTelemetry(t *testing.T) (*receiverTelemetry, *sdkmetric.ManualReader) {
+	reader := sdkmetric.NewManualReader()
+	set := receivertest.NewNopSettings(component.MustNewType("glean"))
+	set.TelemetrySettings.MeterProvider = sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	telemetry, err := newReceiverTelemetry(set)
+	require.NoError(t, err)
+	return telemetry, reader
+}
+
+// collectSum returns the value of the named counter for the given attributes
+func collectSum(t *testing.T, reader *sdkmetric.ManualReader, name string, attrs ...attribute.KeyValue) int64 {
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	want := attribute.NewSet(attrs...)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				if dp.Attributes.Equals(&want) {
+					return dp.Value
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func TestRecordRedactions(t *testing.T) {
+	telemetry, reader := newTestTelemetry(t)
+
+	telemetry.recordRedactions(context.Background(), map[string]int{
+		redactionRulePattern: 2,
+		redactionRuleDeny:    1,
+	})
+	telemetry.recordRedactions(context.Background(), map[string]int{
+		redactionRulePattern: 1,
+	})
+
+	assert.Equal(t, int64(3), collectSum(t, reader, "otelcol_receiver_glean_redacted_values", attribute.String("rule", redactionRulePattern)))
+	assert.Equal(t, int64(1), collectSum(t, reader, "otelcol_receiver_glean_redacted_values", attribute.String("rule", redactionRuleDeny)))
+}
+
+func TestRecordValidationFailures(t *testing.T) {
+	telemetry, reader := newTestTelemetry(t)
+
+	telemetry.recordValidationFailures(context.Background(), []schemaViolation{
+		{Path: "/events/0/name"},
+		{Path: "/events/3/name"},
+		{Path: "/ping_info/seq"},
+	})
+
+	assert.Equal(t, int64(2), collectSum(t, reader, "otelcol_receiver_glean_validation_failures", attribute.String("path", "/events/*/name")))
+	assert.Equal(t, int64(1), collectSum(t, reader, "otelcol_receiver_glean_validation_failures", attribute.String("path", "/ping_info/seq")))
+}
@@ -1,10 +1,16 @@
 package gleanreceiver
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"time"
 )
 
+// deletionRequestDocumentType is the document type of pings sent when a user
+// opts out of telemetry
+const deletionRequestDocumentType = "deletion-request"
+
 // GleanPingRequest contains contexutal data about the ping request
 type GleanPingRequest struct {
 	Namespace       string      `json:"-"`
@@ -12,6 +18,27 @@ type GleanPingRequest struct {
 	DocumentVersion string      `json:"-"`
 	DocumentID      string      `json:"-"`
 	Headers         http.Header `json:"-"`
+	SubmissionTime  time.Time   `json:"-"`
+	SourceTags      []string    `json:"-"`
+	Route           string      `json:"-"`
+
+	// Replayed is set for pings read back from an archive. Their metric
+	// data points are timestamped at ping_info.end_time instead of the time
+	// they are received, and they are not forwarded downstream again.
+	Replayed bool `json:"-"`
+
+	// TimeShift is added to the ping_info times of replayed pings
+	TimeShift time.Duration `json:"-"`
+
+	// RateLimited is set for HTTP pings the rate limits apply to
+	RateLimited bool `json:"-"`
+
+	// ClientIP is the address of the client sending a rate limited ping
+	ClientIP string `json:"-"`
+
+	// Delivery is set for pings the receiver retries itself. It keeps what
+	// earlier attempts already did.
+	Delivery *pingDelivery `json:"-"`
 }
 
 // GleanPing represents the top-level structure of a Glean telemetry ping
@@ -25,32 +52,120 @@ type GleanPing struct {
 
 // ClientInfo contains information about the client device and application
 type ClientInfo struct {
-	ClientID           string `json:"client_id,omitempty"`
-	SessionID          string `json:"session_id,omitempty"`
-	SessionCount       int    `json:"session_count,omitempty"`
-	AppBuild           string `json:"app_build,omitempty"`
-	AppDisplayVersion  string `json:"app_display_version,omitempty"`
-	AppChannel         string `json:"app_channel,omitempty"`
-	TelemetrySDKBuild  string `json:"telemetry_sdk_build,omitempty"`
-	Architecture       string `json:"architecture,omitempty"`
-	DeviceManufacturer string `json:"device_manufacturer,omitempty"`
-	DeviceModel        string `json:"device_model,omitempty"`
-	OS                 string `json:"os,omitempty"`
-	OSVersion          string `json:"os_version,omitempty"`
-	AndroidSDKVersion  string `json:"android_sdk_version,omitempty"`
-	WindowsBuildNumber int    `json:"windows_build_number,omitempty"`
-	Locale             string `json:"locale,omitempty"`
-	BuildDate          string `json:"build_date,omitempty"`
-	FirstRunDate       string `json:"first_run_date,omitempty"`
+	ClientID           string              `json:"client_id,omitempty"`
+	SessionID          string              `json:"session_id,omitempty"`
+	SessionCount       int                 `json:"session_count,omitempty"`
+	AppBuild           string              `json:"app_build,omitempty"`
+	AppDisplayVersion  string              `json:"app_display_version,omitempty"`
+	AppChannel         string              `json:"app_channel,omitempty"`
+	TelemetrySDKBuild  string              `json:"telemetry_sdk_build,omitempty"`
+	Architecture       string              `json:"architecture,omitempty"`
+	DeviceManufacturer string              `json:"device_manufacturer,omitempty"`
+	DeviceModel        string              `json:"device_model,omitempty"`
+	OS                 string              `json:"os,omitempty"`
+	OSVersion          string              `json:"os_version,omitempty"`
+	AndroidSDKVersion  string              `json:"android_sdk_version,omitempty"`
+	WindowsBuildNumber int                 `json:"windows_build_number,omitempty"`
+	Locale             string              `json:"locale,omitempty"`
+	BuildDate          string              `json:"build_date,omitempty"`
+	FirstRunDate       string              `json:"first_run_date,omitempty"`
+	Attribution        *ClientAttribution  `json:"attribution,omitempty"`
+	Distribution       *ClientDistribution `json:"distribution,omitempty"`
+}
+
+// ClientAttribution contains the marketing attribution of the installation
+type ClientAttribution struct {
+	Campaign string         `json:"campaign,omitempty"`
+	Content  string         `json:"content,omitempty"`
+	Medium   string         `json:"medium,omitempty"`
+	Source   string         `json:"source,omitempty"`
+	Term     string         `json:"term,omitempty"`
+	Ext      map[string]any `json:"ext,omitempty"`
+}
+
+// ClientDistribution contains information about the distribution the
+// application was installed from
+type ClientDistribution struct {
+	Name string         `json:"name,omitempty"`
+	Ext  map[string]any `json:"ext,omitempty"`
 }
 
 // PingInfo contains metadata about the ping itself
 type PingInfo struct {
-	Seq       int       `json:"seq"`
-	StartTime time.Time `json:"start_time"`
-	EndTime   time.Time `json:"end_time"`
-	PingType  string    `json:"ping_type"`
-	Reason    string    `json:"reason,omitempty"`
+	Seq         int                   `json:"seq"`
+	StartTime   time.Time             `json:"start_time"`
+	EndTime     time.Time             `json:"end_time"`
+	PingType    string                `json:"ping_type"`
+	Reason      string                `json:"reason,omitempty"`
+	Experiments map[string]Experiment `json:"experiments,omitempty"`
+}
+
+// UnmarshalJSON parses ping_info, accepting the minute-precision timestamps
+// Glean SDKs send (2024-01-28T10:00+01:00) as well as RFC 3339
+func (p *PingInfo) UnmarshalJSON(data []byte) error {
+	type pingInfoAlias PingInfo
+	var raw struct {
+		pingInfoAlias
+		StartTime string `json:"start_time"`
+		EndTime   string `json:"end_time"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*p = PingInfo(raw.pingInfoAlias)
+	var err error
+	if p.StartTime, err = parseGleanTime(raw.StartTime); err != nil {
+		return fmt.Errorf("invalid ping_info.start_time: %w", err)
+	}
+	if p.EndTime, err = parseGleanTime(raw.EndTime); err != nil {
+		return fmt.Errorf("invalid ping_info.end_time: %w", err)
+	}
+	return nil
+}
+
+// shift moves the ping window by d, leaving unset times alone
+func (p *PingInfo) shift(d time.Duration) {
+	if !p.StartTime.IsZero() {
+		p.StartTime = p.StartTime.Add(d)
+	}
+	if !p.EndTime.IsZero() {
+		p.EndTime = p.EndTime.Add(d)
+	}
+}
+
+// gleanTimeLayouts are the timestamp layouts found in ping_info
+var gleanTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+}
+
+// parseGleanTime parses a ping_info timestamp, treating an empty value as
+// the zero time
+func parseGleanTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	var err error
+	for _, layout := range gleanTimeLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// Experiment is an active experiment enrollment reported in ping_info
+type Experiment struct {
+	Branch string          `json:"branch"`
+	Extra  ExperimentExtra `json:"extra,omitempty"`
+}
+
+// ExperimentExtra contains additional experiment enrollment details
+type ExperimentExtra struct {
+	Type         string `json:"type,omitempty"`
+	EnrollmentID string `json:"enrollment_id,omitempty"`
 }
 
 // Event represents a Glean event
@@ -0,0 +1,100 @@
+package gleanreceiver
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPingInfoUnmarshalJSON(t *testing.T) {
+	var pingInfo PingInfo
+	err := json.Unmarshal([]byte(`{
+		"seq": 3,
+		"start_time": "2024-01-28T10:00:00+01:00",
+		"end_time": "2024-01-28T10:05:30.5Z",
+		"reason": "scheduled",
+		"experiments": {
+			"new-onboarding": {"branch": "treatment", "extra": {"type": "nimbus-nimbus", "enrollment_id": "e1"}}
+		}
+	}`), &pingInfo)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, pingInfo.Seq)
+	assert.Equal(t, "scheduled", pingInfo.Reason)
+	assert.True(t, pingInfo.StartTime.Equal(time.Date(2024, 1, 28, 9, 0, 0, 0, time.UTC)))
+	assert.True(t, pingInfo.EndTime.Equal(time.Date(2024, 1, 28, 10, 5, 30, 500000000, time.UTC)))
+	assert.Equal(t, Experiment{
+		Branch: "treatment",
+		Extra:  ExperimentExtra{Type: "nimbus-nimbus", EnrollmentID: "e1"},
+	}, pingInfo.Experiments["new-onboarding"])
+}
+
+func TestPingInfoUnmarshalJSONMinutePrecision(t *testing.T) {
+	var pingInfo PingInfo
+	err := json.Unmarshal([]byte(`{
+		"seq": 0,
+		"start_time": "2024-01-28T10:00+01:00",
+		"end_time": "2024-01-28T10:05-05:00"
+	}`), &pingInfo)
+	require.NoError(t, err)
+
+	assert.True(t, pingInfo.StartTime.Equal(time.Date(2024, 1, 28, 9, 0, 0, 0, time.UTC)))
+	assert.True(t, pingInfo.EndTime.Equal(time.Date(2024, 1, 28, 15, 5, 0, 0, time.UTC)))
+}
+
+func TestParseGleanTime(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "", want: time.Time{}},
+		{value: "2024-01-28T10:00+01:00", want: time.Date(2024, 1, 28, 9, 0, 0, 0, time.UTC)},
+		{value: "2024-01-28T10:00Z", want: time.Date(2024, 1, 28, 10, 0, 0, 0, time.UTC)},
+		{value: "2024-01-28T10:00:30Z", want: time.Date(2024, 1, 28, 10, 0, 30, 0, time.UTC)},
+		{value: "2024-01-28T10Z", wantErr: true},
+		{value: "2024-01-28", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseGleanTime(tt.value)
+		if tt.wantErr {
+			assert.Error(t, err, tt.value)
+			continue
+		}
+		require.NoError(t, err, tt.value)
+		assert.True(t, tt.want.Equal(got), tt.value)
+	}
+}
+
+func TestPingInfoUnmarshalJSONRoundTrip(t *testing.T) {
+	original := PingInfo{
+		Seq:       1,
+		StartTime: time.Date(2024, 1, 28, 10, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2024, 1, 28, 10, 1, 0, 0, time.UTC),
+		PingType:  "metrics",
+	}
+	data, err := json.Marshal(original)
+	require.NoError(t, err)
+
+	var decoded PingInfo
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, original, decoded)
+}
+
+func TestPingInfoUnmarshalJSONInvalidTime(t *testing.T) {
+	var pingInfo PingInfo
+	err := json.Unmarshal([]byte(`{"seq": 0, "start_time": "yesterday", "end_time": ""}`), &pingInfo)
+	assert.Error(t, err)
+}
+
+func TestPingInfoShift(t *testing.T) {
+	pingInfo := PingInfo{StartTime: time.Date(2024, 1, 28, 10, 0, 0, 0, time.UTC)}
+	pingInfo.shift(24 * time.Hour)
+
+	assert.Equal(t, time.Date(2024, 1, 29, 10, 0, 0, 0, time.UTC), pingInfo.StartTime)
+	assert.True(t, pingInfo.EndTime.IsZero())
+}
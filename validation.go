@@ -0,0 +1,174 @@
+package gleanreceiver
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// Actions taken when a ping fails schema validation
+const (
+	validationFailureReject = "reject"
+	validationFailureLog    = "log"
+)
+
+// pingStructureSchema only checks the structure of pings. It is not the
+// upstream Glean schema, which is loaded from schema_path.
+//
+//go:embed schemas/glean-ping-structure.schema.json
+var pingStructureSchema []byte
+
+// schemaViolation is a single reason a ping does not match the schema
+type schemaViolation struct {
+	// Path is the JSON pointer of the offending value within the ping
+	Path string
+	// Message names the schema keyword that failed, such as "pattern" or
+	// "required". It never contains values from the ping, which have not
+	// been through privacy processing or redaction yet.
+	Message string
+}
+
+// pingValidator validates raw ping payloads against the Glean ping schema
+type pingValidator struct {
+	schema *jsonschema.Schema
+}
+
+// newPingValidator compiles the schema at schemaPath, or the built-in ping
+// structure schema when schemaPath is empty
+func newPingValidator(schemaPath string) (*pingValidator, error) {
+	schemaJSON := pingStructureSchema
+	location := "glean-ping-structure.schema.json"
+	if schemaPath != "" {
+		var err error
+		schemaJSON, err = os.ReadFile(schemaPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ping schema: %w", err)
+		}
+		location = schemaPath
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schemaJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ping schema: %w", err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(location, doc); err != nil {
+		return nil, fmt.Errorf("failed to load ping schema: %w", err)
+	}
+	schema, err := compiler.Compile(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile ping schema: %w", err)
+	}
+
+	return &pingValidator{schema: schema}, nil
+}
+
+// validate returns the schema violations of a raw ping payload, sorted by
+// path. Payloads that are not valid JSON are reported as a violation at the
+// document root.
+func (v *pingValidator) validate(body []byte) []schemaViolation {
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return []schemaViolation{{Path: "", Message: "invalid JSON"}}
+	}
+
+	err = v.schema.Validate(instance)
+	if err == nil {
+		return nil
+	}
+
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return []schemaViolation{{Path: "", Message: "schema"}}
+	}
+
+	var violations []schemaViolation
+	v.collectViolations(validationErr, &violations)
+	sort.Slice(violations, func(i, j int) bool {
+		return violations[i].Path < violations[j].Path
+	})
+	return violations
+}
+
+// collectViolations flattens the leaf errors of a validation error tree.
+// Messages are built from the failed keyword rather than the localized error
+// text, which quotes the rejected value for kinds such as pattern and format.
+func (v *pingValidator) collectViolations(err *jsonschema.ValidationError, violations *[]schemaViolation) {
+	if len(err.Causes) == 0 {
+		keyword := strings.Join(err.ErrorKind.KeywordPath(), "/")
+		if keyword == "" {
+			keyword = "schema"
+		}
+		*violations = append(*violations, schemaViolation{
+			Path:    jsonPointer(err.InstanceLocation),
+			Message: keyword,
+		})
+		return
+	}
+	for _, cause := range err.Causes {
+		v.collectViolations(cause, violations)
+	}
+}
+
+// jsonPointer builds an RFC 6901 JSON pointer from path segments
+func jsonPointer(segments []string) string {
+	var sb strings.Builder
+	for _, segment := range segments {
+		sb.WriteByte('/')
+		segment = strings.ReplaceAll(segment, "~", "~0")
+		sb.WriteString(strings.ReplaceAll(segment, "/", "~1"))
+	}
+	return sb.String()
+}
+
+// pathPattern returns the violation path with array indices and the keys
+// chosen by the client, such as metric names, labels, experiment IDs and
+// event extra keys, replaced by *. This keeps the number of distinct paths
+// bounded whatever the pings contain.
+func (v schemaViolation) pathPattern() string {
+	segments := strings.Split(v.Path, "/")
+	pattern := make([]string, len(segments))
+	for i, segment := range segments {
+		if segment != "" && (strings.Trim(segment, "0123456789") == "" || isClientKey(segments[:i])) {
+			pattern[i] = "*"
+			continue
+		}
+		pattern[i] = segment
+	}
+	return strings.Join(pattern, "/")
+}
+
+// isClientKey reports whether the segment following the JSON pointer
+// segments parents is a key of a map validated by additionalProperties
+func isClientKey(parents []string) bool {
+	switch {
+	case len(parents) < 3:
+		return false
+	case parents[1] == "metrics":
+		switch len(parents) {
+		case 3:
+			// /metrics/{type}/{name}
+			return true
+		case 4:
+			// /metrics/labeled_{type}/{name}/{label}
+			return strings.HasPrefix(parents[2], "labeled_")
+		case 5:
+			// /metrics/{type}/{name}/values/{bucket}
+			return parents[4] == "values"
+		}
+	case parents[1] == "ping_info":
+		// /ping_info/experiments/{id}
+		return len(parents) == 3 && parents[2] == "experiments"
+	case parents[1] == "events":
+		// /events/{index}/extra/{key}
+		return len(parents) == 4 && parents[3] == "extra"
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package gleanreceiver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPingValidatorValidPing(t *testing.T) {
+	validator, err := newPingValidator("")
+	require.NoError(t, err)
+
+	body, err := os.ReadFile("example-ping.json")
+	require.NoError(t, err)
+
+	assert.Empty(t, validator.validate(body))
+}
+
+func TestPingValidatorInvalidPing(t *testing.T) {
+	validator, err := newPingValidator("")
+	require.NoError(t, err)
+
+	body := []byte(`{
+		"client_info": {"client_id": "not-a-uuid"},
+		"ping_info": {"seq": "one", "start_time": "2024-01-28T10:00:00Z", "end_time": "2024-01-28T10:01:00Z"},
+		"events": [{"timestamp": 0, "category": "ui", "name": "ok"}, {"timestamp": 0, "category": "ui"}]
+	}`)
+
+	violations := validator.validate(body)
+	require.Len(t, violations, 3)
+	assert.Equal(t, "/client_info/client_id", violations[0].Path)
+	assert.Equal(t, "/events/1", violations[1].Path)
+	assert.Equal(t, "/events/*", violations[1].pathPattern())
+	assert.Equal(t, "/ping_info/seq", violations[2].Path)
+	assert.Equal(t, "pattern", violations[0].Message)
+	assert.Equal(t, "type", violations[2].Message)
+}
+
+func TestPingValidatorInvalidJSON(t *testing.T) {
+	validator, err := newPingValidator("")
+	require.NoError(t, err)
+
+	violations := validator.validate([]byte("{invalid json}"))
+	require.Len(t, violations, 1)
+	assert.Equal(t, "", violations[0].Path)
+}
+
+func TestPingValidatorSchemaPath(t *testing.T) {
+	schemaPath := filepath.Join(t.TempDir(), "schema.json")
+	require.NoError(t, os.WriteFile(schemaPath, []byte(`{"type": "object", "required": ["metrics"]}`), 0o600))
+
+	validator, err := newPingValidator(schemaPath)
+	require.NoError(t, err)
+
+	assert.Len(t, validator.validate([]byte(`{}`)), 1)
+	assert.Empty(t, validator.validate([]byte(`{"metrics": {}}`)))
+
+	_, err = newPingValidator(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestSchemaViolationPathPattern(t *testing.T) {
+	tests := map[string]string{
+		"":                            "",
+		"/client_info/client_id":      "/client_info/client_id",
+		"/events/12":                  "/events/*",
+		"/events/3/extra/user.chosen": "/events/*/extra/*",
+		"/metrics/counter":            "/metrics/counter",
+		"/metrics/counter/app.opened": "/metrics/counter/*",
+		"/metrics/labeled_counter/app.errors/timeout": "/metrics/labeled_counter/*/*",
+		"/metrics/timespan/app.startup/value":         "/metrics/timespan/*/value",
+		"/metrics/memory_distribution/heap/values/x":  "/metrics/memory_distribution/*/values/*",
+		"/ping_info/experiments/my-experiment/branch": "/ping_info/experiments/*/branch",
+		"/ping_info/seq": "/ping_info/seq",
+	}
+	for path, want := range tests {
+		assert.Equal(t, want, schemaViolation{Path: path}.pathPattern(), path)
+	}
+}
+
+func TestJSONPointer(t *testing.T) {
+	assert.Equal(t, "", jsonPointer(nil))
+	assert.Equal(t, "/metrics/counter/a~1b~0c", jsonPointer([]string{"metrics", "counter", "a/b~c"}))
+}